@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/matthewchivers/txt2llm/pkg/cli"
+	"github.com/matthewchivers/txt2llm/pkg/diff"
+	"github.com/matthewchivers/txt2llm/pkg/unpack"
+)
+
+// runApply applies the unified diffs found in text read from a file or stdin
+// to files under the configured root, reporting each hunk and saving the
+// ones that fail to a .rej file next to their target.
+func runApply(args []string, stdin io.Reader, stdout io.Writer) error {
+	cfg, input, err := cli.ParseApplyArgs(args)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if input == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(input)
+	}
+	if err != nil {
+		return err
+	}
+
+	patches := mergePatches(diff.Extract(string(data)))
+	if len(patches) == 0 {
+		return errors.New("no unified diffs found")
+	}
+	files := make([]unpack.File, len(patches))
+	for i, p := range patches {
+		files[i] = unpack.File{Path: p.Path()}
+	}
+	changes, err := unpack.Plan(cfg.Root, files)
+	if err != nil {
+		return err
+	}
+
+	var writes, rejects []unpack.Change
+	var deletes []string
+	total, failed := 0, 0
+	for i, p := range patches {
+		c := changes[i]
+		total += len(p.Hunks)
+		if note, skip := checkTarget(c, p); note != "" {
+			fmt.Fprintf(stdout, "%s: %s\n", c.Path, note)
+			if !skip {
+				failed += len(p.Hunks)
+				rejects = append(rejects, reject(c, p.Hunks))
+			}
+			continue
+		}
+		content, results := diff.Apply(c.Old, p.Hunks, cfg.Fuzz)
+		var rejected []diff.Hunk
+		for j, r := range results {
+			fmt.Fprintf(stdout, "%s: hunk %d %s\n", c.Path, j+1, r)
+			if !r.Applied {
+				rejected = append(rejected, p.Hunks[j])
+			}
+		}
+		failed += len(rejected)
+		if len(rejected) > 0 {
+			rejects = append(rejects, reject(c, rejected))
+		}
+		switch {
+		case p.IsDelete() && len(rejected) == 0 && len(content) == 0:
+			deletes = append(deletes, c.Target)
+		case len(rejected) < len(p.Hunks):
+			c.Content = content
+			writes = append(writes, c)
+		}
+	}
+	if !cfg.DryRun {
+		if err := unpack.Apply(writes); err != nil {
+			return err
+		}
+		for _, target := range deletes {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+		if err := saveRejects(cfg.Root, rejects, stdout); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d hunks failed", failed, total)
+	}
+	return nil
+}
+
+// checkTarget returns a note if p does not fit whether its target c exists:
+// it edits a file that does not exist, or creates one that already does.
+// skip is set if the file it creates already holds exactly what it would
+// create, so applying the same patch twice leaves the file as it is.
+func checkTarget(c unpack.Change, p diff.FilePatch) (note string, skip bool) {
+	switch {
+	case !c.Exists && !p.IsNew():
+		return "file does not exist", false
+	case c.Exists && p.IsNew():
+		if created, _ := diff.Apply(nil, p.Hunks, 0); bytes.Equal(created, c.Old) {
+			return "already created", true
+		}
+		return "file already exists", false
+	}
+	return "", false
+}
+
+// mergePatches combines patches for the same file, keeping their hunks in
+// the order they appeared, since replies often split one file's changes
+// across several diffs.
+func mergePatches(patches []diff.FilePatch) []diff.FilePatch {
+	var merged []diff.FilePatch
+	index := map[string]int{}
+	for _, p := range patches {
+		if i, ok := index[p.Path()]; ok {
+			merged[i].Hunks = append(merged[i].Hunks, p.Hunks...)
+			continue
+		}
+		index[p.Path()] = len(merged)
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// reject returns the .rej file holding the hunks that could not be applied
+// to c.
+func reject(c unpack.Change, hunks []diff.Hunk) unpack.Change {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", c.Path, c.Path)
+	for _, h := range hunks {
+		b.WriteString(h.String())
+	}
+	return unpack.Change{File: unpack.File{Path: c.Path + ".rej", Content: []byte(b.String())}}
+}
+
+// saveRejects writes the rejected hunks under root, checking the .rej paths
+// the same way as the files they belong to.
+func saveRejects(root string, rejects []unpack.Change, stdout io.Writer) error {
+	files := make([]unpack.File, len(rejects))
+	for i, r := range rejects {
+		files[i] = r.File
+	}
+	changes, err := unpack.Plan(root, files)
+	if err != nil {
+		return err
+	}
+	if err := unpack.Apply(changes); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		fmt.Fprintf(stdout, "Saved rejected hunks to %s\n", c.Path)
+	}
+	return nil
+}
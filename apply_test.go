@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunApply verifies that apply patches, creates and deletes files, reports every hunk, saves failed hunks
+// to a .rej file and does not create a file that already exists.
+func TestRunApply(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0644))
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+	write("a.txt", "one\ntwo\nthree\n")
+	write("gone.txt", "bye\n")
+
+	reply := "Try this:\n```diff\n" +
+		"--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n one\n-two\n+TWO\n three\n" +
+		"@@ -20 +20 @@\n-missing\n+nope\n" +
+		"--- /dev/null\n+++ b/new/b.txt\n@@ -0,0 +1 @@\n+b\n" +
+		"--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n" +
+		"```\n"
+
+	var out bytes.Buffer
+	err := runApply([]string{"--root", root, "--dry-run"}, strings.NewReader(reply), &out)
+	assert.EqualError(t, err, "1 of 4 hunks failed")
+	assert.Equal(t, "a.txt: hunk 1 applied\na.txt: hunk 2 FAILED\nnew/b.txt: hunk 1 applied\ngone.txt: hunk 1 applied\n", out.String())
+	assert.Equal(t, "one\ntwo\nthree\n", read("a.txt"), "a dry run must not write")
+	assert.NoFileExists(t, filepath.Join(root, "a.txt.rej"))
+
+	out.Reset()
+	err = runApply([]string{"--root", root}, strings.NewReader(reply), &out)
+	assert.EqualError(t, err, "1 of 4 hunks failed")
+	assert.Contains(t, out.String(), "Saved rejected hunks to a.txt.rej\n")
+	assert.Equal(t, "one\nTWO\nthree\n", read("a.txt"))
+	assert.Equal(t, "--- a/a.txt\n+++ b/a.txt\n@@ -20 +20 @@\n-missing\n+nope\n", read("a.txt.rej"))
+	assert.Equal(t, "b\n", read("new/b.txt"))
+	assert.NoFileExists(t, filepath.Join(root, "gone.txt"))
+
+	out.Reset()
+	creation := "--- /dev/null\n+++ b/new/b.txt\n@@ -0,0 +1 @@\n+b\n"
+	require.NoError(t, runApply([]string{"--root", root}, strings.NewReader(creation), &out))
+	assert.Equal(t, "new/b.txt: already created\n", out.String())
+	assert.Equal(t, "b\n", read("new/b.txt"), "creating a file twice must not duplicate it")
+
+	out.Reset()
+	err = runApply([]string{"--root", root}, strings.NewReader(strings.ReplaceAll(creation, "+b", "+other")), &out)
+	assert.EqualError(t, err, "1 of 1 hunks failed")
+	assert.Contains(t, out.String(), "new/b.txt: file already exists\n")
+	assert.Equal(t, "b\n", read("new/b.txt"))
+
+	err = runApply([]string{"--root", root}, strings.NewReader("no diffs here"), &out)
+	assert.EqualError(t, err, "no unified diffs found")
+
+	err = runApply([]string{"--root", root}, strings.NewReader("--- a/../x\n+++ b/../x\n@@ -1 +1 @@\n-a\n+b\n"), &out)
+	assert.ErrorContains(t, err, "outside")
+}
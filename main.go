@@ -2,23 +2,181 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/matthewchivers/txt2llm/pkg/cli"
 	"github.com/matthewchivers/txt2llm/pkg/output"
+	"github.com/matthewchivers/txt2llm/pkg/redact"
 	"github.com/matthewchivers/txt2llm/pkg/resolve"
+	"github.com/matthewchivers/txt2llm/pkg/tokens"
+	"github.com/matthewchivers/txt2llm/pkg/txt2llm"
 )
 
 func main() {
-	cfg := cli.Parse()
-	patterns := cli.Patterns()
-	files, err := resolve.Files(patterns, cfg.Recursive)
+	var err error
+	switch {
+	case len(os.Args) > 1 && os.Args[1] == "unpack":
+		err = runUnpack(os.Args[2:], os.Stdin, os.Stdout)
+	case len(os.Args) > 1 && os.Args[1] == "apply":
+		err = runApply(os.Args[2:], os.Stdin, os.Stdout)
+	default:
+		err = run(cli.Parse(), cli.Patterns())
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
-	outPaths := output.Paths(files, cfg.Relative)
-	output.Header(cfg.MarkerPrefix, cfg.MarkerSuffix)
-	output.Markers(files, outPaths, cfg.MarkerPrefix, cfg.MarkerSuffix)
+}
+
+// run writes the bundle described by cfg to stdout or the output file.
+func run(cfg cli.Config, patterns []string) error {
+	if cfg.PrintConfig {
+		return cli.PrintConfig(os.Stdout, cfg, patterns)
+	}
+	opts, err := bundleOptions(cfg, patterns)
+	if err != nil {
+		return err
+	}
+
+	if cfg.List {
+		stats, err := txt2llm.List(context.Background(), opts, cfg.Sizes)
+		if err != nil {
+			return err
+		}
+		list(os.Stdout, stats, cfg.Sizes)
+		return nil
+	}
+	if cfg.Output == "" {
+		stats, err := txt2llm.Bundle(context.Background(), opts, os.Stdout)
+		if err != nil {
+			return err
+		}
+		report(cfg, stats)
+		return nil
+	}
+	out, err := output.CreateAtomic(cfg.Output)
+	if err != nil {
+		return err
+	}
+	stats, err := txt2llm.Bundle(context.Background(), opts, out)
+	if err != nil {
+		out.Abort()
+		return err
+	}
+	if err := out.Commit(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d bytes to %s\n", out.Written(), cfg.Output)
+	report(cfg, stats)
+	return nil
+}
+
+// list prints the files a bundle would include, one per line, optionally
+// preceded by their size and tokens. Files that would be skipped when read are
+// shown with the reason, and files with secrets to redact with their number.
+func list(w io.Writer, stats txt2llm.Stats, sizes bool) {
+	for _, f := range stats.Files {
+		switch {
+		case f.Skipped == "ignored", f.Skipped == "sensitive" && !sizes:
+		case !sizes:
+			fmt.Fprintln(w, f.Path)
+		case f.Skipped != "":
+			fmt.Fprintf(w, "%10s %8s  %s (%s)\n", "-", "-", f.Path, f.Skipped)
+		case len(f.Redacted) == 1:
+			fmt.Fprintf(w, "%10d %8d  %s (1 secret redacted)\n", f.Bytes, f.Tokens, f.Path)
+		case len(f.Redacted) > 1:
+			fmt.Fprintf(w, "%10d %8d  %s (%d secrets redacted)\n", f.Bytes, f.Tokens, f.Path, len(f.Redacted))
+		default:
+			fmt.Fprintf(w, "%10d %8d  %s\n", f.Bytes, f.Tokens, f.Path)
+		}
+	}
+}
+
+// report prints the summaries requested by cfg to stderr.
+func report(cfg cli.Config, stats txt2llm.Stats) {
+	if cfg.CountTokens {
+		for _, f := range stats.Files {
+			if f.Skipped == "" {
+				fmt.Fprintf(os.Stderr, "%8d  %s\n", f.Tokens, f.Path)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%8d  total, with delimiters\n", stats.Tokens)
+	}
+	if cfg.Stats {
+		stats.WriteTable(os.Stderr)
+	}
+}
+
+// bundleOptions converts CLI configuration to library options.
+func bundleOptions(cfg cli.Config, patterns []string) (txt2llm.Options, error) {
+	format, err := output.ParseFormat(cfg.Format)
+	if err != nil {
+		return txt2llm.Options{}, err
+	}
+	binary, err := output.ParseBinaryMode(cfg.Binary)
+	if err != nil {
+		return txt2llm.Options{}, err
+	}
+	onCollision, err := output.ParseCollisionMode(cfg.OnCollision)
+	if err != nil {
+		return txt2llm.Options{}, err
+	}
+	secrets, err := output.ParseSecretMode(cfg.OnSecret)
+	if err != nil {
+		return txt2llm.Options{}, err
+	}
+	onBudget, err := output.ParseBudgetMode(cfg.OnBudget)
+	if err != nil {
+		return txt2llm.Options{}, err
+	}
+	if cfg.Jobs < 0 || cfg.MaxBufferedMB < 0 || cfg.StreamOverMB < 0 {
+		return txt2llm.Options{}, fmt.Errorf("--jobs, --max-buffered-mb and --stream-over-mb must not be negative")
+	}
+	var redactor *redact.Redactor
+	if cfg.RedactRules != "" {
+		rules, err := redact.LoadRules(cfg.RedactRules)
+		if err != nil {
+			return txt2llm.Options{}, err
+		}
+		redactor = redact.New(rules...)
+	}
+	var tokenizer tokens.Counter = tokens.Heuristic{}
+	if cfg.Vocab != "" {
+		if tokenizer, err = tokens.LoadBPE(cfg.Vocab); err != nil {
+			return txt2llm.Options{}, err
+		}
+	}
+	return txt2llm.Options{
+		Patterns:      patterns,
+		Recursive:     cfg.Recursive,
+		Relative:      cfg.Relative,
+		NoGitignore:   cfg.NoGitignore,
+		Include:       cfg.Include,
+		Exclude:       cfg.Exclude,
+		Git:           resolve.Git{Tracked: cfg.GitTracked, Changed: cfg.GitChanged, Staged: cfg.GitStaged},
+		Rev:           cfg.Rev,
+		DiffBase:      cfg.Diff,
+		DiffContext:   cfg.DiffContext,
+		DiffFullUnder: cfg.DiffFullUnder,
+		Format:        format,
+		MarkerPrefix:  cfg.MarkerPrefix,
+		MarkerSuffix:  cfg.MarkerSuffix,
+		OnCollision:   onCollision,
+		Binary:        binary,
+		Secrets:       secrets,
+		Redactor:      redactor,
+		OutputPath:    cfg.Output,
+		Warnings:      os.Stderr,
+		Tokenizer:     tokenizer,
+		MaxTokens:     cfg.MaxTokens,
+		OnBudget:      onBudget,
+		Tree:          cfg.Tree,
+		TreeSizes:     cfg.TreeSizes,
+		Jobs:          cfg.Jobs,
+		MaxBuffered:   int64(cfg.MaxBufferedMB) << 20,
+		StreamOver:    int64(cfg.StreamOverMB) << 20,
+	}, nil
 }
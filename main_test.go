@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/matthewchivers/txt2llm/pkg/txt2llm"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -12,6 +15,8 @@ import (
 
 // TestMainUnit verifies the main function correctly processes command-line arguments and outputs files with proper markers.
 func TestMainUnit(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
 	// Create a test file
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "test.txt")
@@ -188,4 +193,99 @@ func TestMainUnit(t *testing.T) {
 		assert.Contains(t, output, "package main")
 		assert.Contains(t, output, "<<<END:test2.go>>>")
 	})
+
+	t.Run("with output file", func(t *testing.T) {
+		// Save original args, stderr, and command line
+		oldArgs := os.Args
+		oldErr := os.Stderr
+		oldCmdLine := pflag.CommandLine
+		defer func() {
+			os.Args = oldArgs
+			os.Stderr = oldErr
+			pflag.CommandLine = oldCmdLine
+		}()
+
+		// Reset pflag state
+		pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+		// Write a previous bundle that the directory walk would otherwise pick up
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "bundle.txt"), []byte("old bundle"), 0644))
+		os.Args = []string{"txt2llm", "--relative", "-o", "bundle.txt", "."}
+
+		// Capture stderr
+		r, w, _ := os.Pipe()
+		os.Stderr = w
+
+		// Call main
+		main()
+
+		// Close and restore
+		w.Close()
+		os.Stderr = oldErr
+
+		buf := make([]byte, 1024)
+		n, _ := r.Read(buf)
+		stderr := string(buf[:n])
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, "bundle.txt"))
+		require.NoError(t, err)
+		output := string(data)
+
+		// Verify the bundle was written without including itself
+		assert.Contains(t, output, "<<<START:test.txt>>>")
+		assert.NotContains(t, output, "old bundle")
+		assert.NotContains(t, output, "START:bundle.txt")
+		assert.Contains(t, stderr, "Skipping output file")
+		assert.Contains(t, stderr, fmt.Sprintf("Wrote %d bytes to bundle.txt", len(data)))
+	})
+
+	t.Run("with output file in a recursive walk", func(t *testing.T) {
+		// Save original args, stderr, and command line
+		oldArgs := os.Args
+		oldErr := os.Stderr
+		oldCmdLine := pflag.CommandLine
+		defer func() {
+			os.Args = oldArgs
+			os.Stderr = oldErr
+			pflag.CommandLine = oldCmdLine
+		}()
+
+		// Reset pflag state
+		pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+		os.Args = []string{"txt2llm", "--recursive", "--relative", "-o", "walked.txt", "."}
+		os.Stderr, _ = os.Open(os.DevNull)
+
+		// Call main
+		main()
+		os.Stderr = oldErr
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, "walked.txt"))
+		require.NoError(t, err)
+		output := string(data)
+
+		// Verify neither the output file nor its temporary file was bundled
+		assert.Contains(t, output, "<<<START:test.txt>>>")
+		assert.NotContains(t, output, "walked.txt")
+		assert.NotContains(t, output, ".tmp-")
+	})
+}
+
+// TestList verifies that --list prints one path per line, with sizes, tokens and secrets to redact when asked, and
+// never ignored paths.
+func TestList(t *testing.T) {
+	stats := txt2llm.Stats{Files: []txt2llm.FileStats{
+		{Path: "main.go", Bytes: 120, Tokens: 30},
+		{Path: "config.go", Bytes: 80, Tokens: 20, Redacted: []string{"password", "jwt"}},
+		{Path: "logo.png", Skipped: "binary"},
+		{Path: "vendor/", Skipped: "ignored"},
+	}}
+
+	var buf bytes.Buffer
+	list(&buf, stats, false)
+	assert.Equal(t, "main.go\nconfig.go\nlogo.png\n", buf.String())
+
+	buf.Reset()
+	list(&buf, stats, true)
+	assert.Equal(t, "       120       30  main.go\n        80       20  config.go (2 secrets redacted)\n"+
+		"         -        -  logo.png (binary)\n", buf.String())
 }
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+// ApplyConfig holds parsed flags for the apply subcommand.
+type ApplyConfig struct {
+	Root   string
+	Fuzz   int
+	DryRun bool
+}
+
+// ParseApplyArgs parses the arguments following "apply", returning the
+// configuration and the input file, which is "-" for stdin.
+func ParseApplyArgs(args []string) (ApplyConfig, string, error) {
+	var cfg ApplyConfig
+	fs := pflag.NewFlagSet("txt2llm apply", pflag.ContinueOnError)
+	fs.StringVar(&cfg.Root, "root", ".", "Directory the patched paths are relative to; paths may not escape it")
+	fs.IntVar(&cfg.Fuzz, "fuzz", 2, "Context lines a hunk may ignore at each end to find where it applies")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "Report which hunks would apply without writing anything")
+	if err := fs.Parse(args); err != nil {
+		return ApplyConfig{}, "", err
+	}
+	if cfg.Fuzz < 0 {
+		return ApplyConfig{}, "", fmt.Errorf("invalid --fuzz %d: must not be negative", cfg.Fuzz)
+	}
+	switch fs.NArg() {
+	case 0:
+		return cfg, "-", nil
+	case 1:
+		return cfg, fs.Arg(0), nil
+	}
+	return ApplyConfig{}, "", fmt.Errorf("apply takes at most one input file, got %d", fs.NArg())
+}
@@ -2,30 +2,131 @@
 package cli
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/spf13/pflag"
 )
 
-// Config holds parsed CLI flags.
+// Config holds parsed CLI flags. The yaml keys match the flag names, which
+// are also the keys accepted in configuration files.
 type Config struct {
-	Recursive    bool
-	Relative     bool
-	MarkerPrefix string
-	MarkerSuffix string
+	Recursive     bool     `yaml:"recursive"`
+	Relative      bool     `yaml:"relative"`
+	NoGitignore   bool     `yaml:"no-gitignore"`
+	Include       []string `yaml:"include"`
+	Exclude       []string `yaml:"exclude"`
+	GitTracked    bool     `yaml:"git-tracked"`
+	GitChanged    string   `yaml:"git-changed"`
+	GitStaged     bool     `yaml:"git-staged"`
+	Rev           string   `yaml:"rev"`
+	Diff          string   `yaml:"diff"`
+	DiffContext   int      `yaml:"diff-context"`
+	DiffFullUnder int      `yaml:"diff-full-under"`
+	Format        string   `yaml:"format"`
+	Output        string   `yaml:"output"`
+	MarkerPrefix  string   `yaml:"marker-prefix"`
+	MarkerSuffix  string   `yaml:"marker-suffix"`
+	OnCollision   string   `yaml:"on-collision"`
+	Binary        string   `yaml:"binary"`
+	OnSecret      string   `yaml:"on-secret"`
+	RedactRules   string   `yaml:"redact-rules"`
+	MaxTokens     int      `yaml:"max-tokens"`
+	OnBudget      string   `yaml:"on-budget"`
+	Vocab         string   `yaml:"vocab"`
+	CountTokens   bool     `yaml:"count-tokens"`
+	Stats         bool     `yaml:"stats"`
+	List          bool     `yaml:"list"`
+	Sizes         bool     `yaml:"sizes"`
+	Tree          bool     `yaml:"tree"`
+	TreeSizes     bool     `yaml:"tree-sizes"`
+	Jobs          int      `yaml:"jobs"`
+	MaxBufferedMB int      `yaml:"max-buffered-mb"`
+	StreamOverMB  int      `yaml:"stream-over-mb"`
+	Profile       string   `yaml:"-"`
+	PrintConfig   bool     `yaml:"-"`
 }
 
-// Parse parses command-line flags and returns configuration.
+// patterns holds the patterns found by Parse for Patterns.
+var patterns []string
+
+// Parse parses command-line flags, layered over any configuration files, and
+// returns configuration. It exits the process with status 2, as pflag does
+// for a bad flag, if the flags or configuration files are invalid; later
+// errors are for the caller, which exits with 1.
 func Parse() Config {
 	var cfg Config
-	pflag.BoolVar(&cfg.Recursive, "recursive", false, "Process directories recursively")
-	pflag.BoolVar(&cfg.Relative, "relative", false, "Use paths relative to current directory in output")
-	pflag.StringVar(&cfg.MarkerPrefix, "marker-prefix", "<<<", "Prefix for start/end marker lines")
-	pflag.StringVar(&cfg.MarkerSuffix, "marker-suffix", ">>>", "Suffix for start/end marker lines")
+	register(pflag.CommandLine, &cfg)
 	pflag.CommandLine.SetInterspersed(true)
 	pflag.Parse()
+	var err error
+	if patterns, err = applyConfigFiles(pflag.CommandLine, pflag.Args(), cfg.Profile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 	return cfg
 }
 
-// Patterns returns positional arguments treated as patterns.
+// ParseArgs parses args (excluding the program name) without touching global
+// flag state, returning the configuration and the positional patterns. Flags
+// are layered over any configuration files.
+func ParseArgs(args []string) (Config, []string, error) {
+	var cfg Config
+	fs := pflag.NewFlagSet("txt2llm", pflag.ContinueOnError)
+	register(fs, &cfg)
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+	pats, err := applyConfigFiles(fs, fs.Args(), cfg.Profile)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, pats, nil
+}
+
+// register defines every flag on fs, storing values in cfg.
+func register(fs *pflag.FlagSet, cfg *Config) {
+	fs.BoolVar(&cfg.Recursive, "recursive", false, "Process directories recursively")
+	fs.BoolVar(&cfg.Relative, "relative", false, "Use paths relative to current directory in output")
+	fs.BoolVar(&cfg.NoGitignore, "no-gitignore", false, "Include files ignored by .gitignore when walking directories and globs")
+	fs.StringArrayVar(&cfg.Include, "include", nil, "Only include files matching this glob (repeatable)")
+	fs.StringArrayVar(&cfg.Exclude, "exclude", nil, "Exclude files matching this glob; wins over --include (repeatable)")
+	fs.BoolVar(&cfg.GitTracked, "git-tracked", false, "Take files tracked by git; patterns narrow the selection")
+	fs.StringVar(&cfg.GitChanged, "git-changed", "", "Take files changed since the merge base with a revision, including uncommitted and untracked ones; "+
+		"write --git-changed=main, as --git-changed main reads main as a pattern")
+	fs.Lookup("git-changed").NoOptDefVal = "HEAD"
+	fs.BoolVar(&cfg.GitStaged, "git-staged", false, "Take files with staged changes")
+	fs.StringVar(&cfg.Rev, "rev", "", "Read paths and contents from this git revision (commit, tag or branch) instead of the working tree")
+	fs.StringVar(&cfg.Diff, "diff", "", "Emit each file as a unified diff against this git revision or directory, skipping unchanged files")
+	fs.IntVar(&cfg.DiffContext, "diff-context", 3, "Unchanged lines to show around each change with --diff")
+	fs.IntVar(&cfg.DiffFullUnder, "diff-full-under", 0, "With --diff, also include the whole of each changed file smaller than this many bytes")
+	fs.StringVar(&cfg.Format, "format", "markers", "Output format: markers, xml, markdown, json or jsonl")
+	fs.StringVarP(&cfg.Output, "output", "o", "", "Write output to this file (atomically) instead of stdout")
+	fs.StringVar(&cfg.MarkerPrefix, "marker-prefix", "<<<", "Prefix for start/end marker lines")
+	fs.StringVar(&cfg.MarkerSuffix, "marker-suffix", ">>>", "Suffix for start/end marker lines")
+	fs.StringVar(&cfg.OnCollision, "on-collision", "nonce", "When a file contains the markers: nonce (make markers unique) or error")
+	fs.StringVar(&cfg.Binary, "binary", "skip", "How to handle binary files: skip, placeholder or include")
+	fs.StringVar(&cfg.OnSecret, "on-secret", "redact", "Secrets and sensitive files such as .env: redact (mask secrets, skip files), error or off")
+	fs.StringVar(&cfg.RedactRules, "redact-rules", "", "YAML file of extra redaction rules (regex, replacement, pseudonymisation) for internal names and IDs")
+	fs.IntVar(&cfg.MaxTokens, "max-tokens", 0, "Token budget for the whole output (0 means no limit)")
+	fs.StringVar(&cfg.OnBudget, "on-budget", "stop", "When --max-tokens is reached: stop, truncate or error")
+	fs.StringVar(&cfg.Vocab, "vocab", "", "Count tokens exactly with this tiktoken-format BPE vocabulary (e.g. cl100k_base.tiktoken)")
+	fs.BoolVar(&cfg.CountTokens, "count-tokens", false, "Print per-file and total token counts to stderr")
+	fs.BoolVar(&cfg.Tree, "tree", false, "Print a tree of the included files before the first file")
+	fs.BoolVar(&cfg.TreeSizes, "tree-sizes", false, "Like --tree, annotating each file and directory with its size and tokens")
+	fs.IntVarP(&cfg.Jobs, "jobs", "j", 8, "Number of files to read at once; output order is unaffected")
+	fs.IntVar(&cfg.MaxBufferedMB, "max-buffered-mb", 256, "Cap on file contents read ahead of the output, in MiB (0 means no limit)")
+	fs.IntVar(&cfg.StreamOverMB, "stream-over-mb", 64, "Copy files larger than this many MiB in chunks instead of reading them whole (0 never streams)")
+	fs.BoolVar(&cfg.List, "list", false, "Print the resolved file paths instead of their contents")
+	fs.BoolVar(&cfg.List, "dry-run", false, "Same as --list")
+	fs.BoolVar(&cfg.Sizes, "sizes", false, "With --list, also print each file's size in bytes and estimated tokens")
+	fs.BoolVar(&cfg.Stats, "stats", false, "Print a table of bytes, lines and tokens per file, and skipped files, to stderr")
+	fs.StringVarP(&cfg.Profile, "profile", "p", "", "Use this named profile from .txt2llm.yaml")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "Print the effective configuration, after merging files and flags, and exit")
+}
+
+// Patterns returns the patterns found by Parse: positional arguments, or the
+// configured patterns when none are given.
 func Patterns() []string {
-	return append([]string{}, pflag.Args()...)
+	return append([]string{}, patterns...)
 }
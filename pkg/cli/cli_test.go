@@ -6,10 +6,13 @@ import (
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestParse verifies that the Parse function correctly parses command-line flags into a Config struct.
 func TestParse(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
 	tests := []struct {
 		name     string
 		args     []string
@@ -19,50 +22,404 @@ func TestParse(t *testing.T) {
 			name: "default values",
 			args: []string{},
 			expected: Config{
-				Recursive:    false,
-				Relative:     false,
-				MarkerPrefix: "<<<",
-				MarkerSuffix: ">>>",
+				Recursive:     false,
+				Relative:      false,
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				Format:        "markers",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
 			},
 		},
 		{
 			name: "recursive flag",
 			args: []string{"--recursive"},
 			expected: Config{
-				Recursive:    true,
-				Relative:     false,
-				MarkerPrefix: "<<<",
-				MarkerSuffix: ">>>",
+				Recursive:     true,
+				Relative:      false,
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				Format:        "markers",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
 			},
 		},
 		{
 			name: "relative flag",
 			args: []string{"--relative"},
 			expected: Config{
-				Recursive:    false,
-				Relative:     true,
-				MarkerPrefix: "<<<",
-				MarkerSuffix: ">>>",
+				Recursive:     false,
+				Relative:      true,
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				Format:        "markers",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+			},
+		},
+		{
+			name: "no-gitignore flag",
+			args: []string{"--no-gitignore"},
+			expected: Config{
+				NoGitignore:   true,
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				Format:        "markers",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+			},
+		},
+		{
+			name: "repeatable include and exclude",
+			args: []string{"--include", "src/**", "--exclude", "*_test.go", "--exclude", "**/*.{pb,gen}.go"},
+			expected: Config{
+				Include:       []string{"src/**"},
+				Exclude:       []string{"*_test.go", "**/*.{pb,gen}.go"},
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				Format:        "markers",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+			},
+		},
+		{
+			name: "binary mode",
+			args: []string{"--binary", "placeholder"},
+			expected: Config{
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				Format:        "markers",
+				OnCollision:   "nonce",
+				Binary:        "placeholder",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+			},
+		},
+		{
+			name: "secret mode",
+			args: []string{"--on-secret", "error", "--redact-rules", "redact.yaml"},
+			expected: Config{
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				Format:        "markers",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "error",
+				RedactRules:   "redact.yaml",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+			},
+		},
+		{
+			name: "xml format",
+			args: []string{"--format", "xml"},
+			expected: Config{
+				Format:        "xml",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+			},
+		},
+		{
+			name: "collision mode",
+			args: []string{"--on-collision", "error"},
+			expected: Config{
+				Format:        "markers",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "error",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+			},
+		},
+		{
+			name: "output file shorthand",
+			args: []string{"-o", "bundle.txt"},
+			expected: Config{
+				Format:        "markers",
+				Output:        "bundle.txt",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+			},
+		},
+		{
+			name: "token budget",
+			args: []string{"--max-tokens", "8000", "--on-budget", "truncate", "--vocab", "cl100k.tiktoken", "--count-tokens"},
+			expected: Config{
+				Format:        "markers",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				MaxTokens:     8000,
+				OnBudget:      "truncate",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+				Vocab:         "cl100k.tiktoken",
+				CountTokens:   true,
+			},
+		},
+		{
+			name: "stats flag",
+			args: []string{"--stats"},
+			expected: Config{
+				Format:        "markers",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+				Stats:         true,
+			},
+		},
+		{
+			name: "list flag",
+			args: []string{"--list", "--sizes"},
+			expected: Config{
+				Format:        "markers",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+				List:          true,
+				Sizes:         true,
+			},
+		},
+		{
+			name: "dry-run alias",
+			args: []string{"--dry-run"},
+			expected: Config{
+				Format:        "markers",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+				List:          true,
+			},
+		},
+		{
+			name: "tree flags",
+			args: []string{"--tree", "--tree-sizes"},
+			expected: Config{
+				Format:        "markers",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+				Tree:          true,
+				TreeSizes:     true,
+			},
+		},
+		{
+			name: "git sources",
+			args: []string{"--git-changed", "--git-staged"},
+			expected: Config{
+				Format:        "markers",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+				GitChanged:    "HEAD",
+				GitStaged:     true,
+			},
+		},
+		{
+			name: "git changed since a base",
+			args: []string{"--git-changed=main", "--git-tracked"},
+			expected: Config{
+				Format:        "markers",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+				GitChanged:    "main",
+				GitTracked:    true,
+			},
+		},
+		{
+			name: "revision",
+			args: []string{"--rev", "v1.2.0"},
+			expected: Config{
+				Format:        "markers",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+				Rev:           "v1.2.0",
+			},
+		},
+		{
+			name: "diff mode",
+			args: []string{"--diff", "main", "--diff-context", "1", "--diff-full-under", "4096"},
+			expected: Config{
+				Format:        "markers",
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				Diff:          "main",
+				DiffContext:   1,
+				DiffFullUnder: 4096,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
+			},
+		},
+		{
+			name: "read concurrency and streaming",
+			args: []string{"-j", "32", "--max-buffered-mb", "64", "--stream-over-mb", "0"},
+			expected: Config{
+				MarkerPrefix:  "<<<",
+				MarkerSuffix:  ">>>",
+				Format:        "markers",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          32,
+				MaxBufferedMB: 64,
 			},
 		},
 		{
 			name: "custom markers",
 			args: []string{"--marker-prefix", "[[[", "--marker-suffix", "]]]"},
 			expected: Config{
-				Recursive:    false,
-				Relative:     false,
-				MarkerPrefix: "[[[",
-				MarkerSuffix: "]]]",
+				Recursive:     false,
+				Relative:      false,
+				MarkerPrefix:  "[[[",
+				MarkerSuffix:  "]]]",
+				Format:        "markers",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
 			},
 		},
 		{
 			name: "all flags combined",
 			args: []string{"--recursive", "--relative", "--marker-prefix", "***", "--marker-suffix", "---"},
 			expected: Config{
-				Recursive:    true,
-				Relative:     true,
-				MarkerPrefix: "***",
-				MarkerSuffix: "---",
+				Recursive:     true,
+				Relative:      true,
+				MarkerPrefix:  "***",
+				MarkerSuffix:  "---",
+				Format:        "markers",
+				OnCollision:   "nonce",
+				Binary:        "skip",
+				OnSecret:      "redact",
+				OnBudget:      "stop",
+				DiffContext:   3,
+				Jobs:          8,
+				MaxBufferedMB: 256,
+				StreamOverMB:  64,
 			},
 		},
 	}
@@ -86,6 +443,8 @@ func TestParse(t *testing.T) {
 
 // TestPatterns verifies that the Patterns function correctly extracts positional arguments while ignoring flags.
 func TestPatterns(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
 	tests := []struct {
 		name     string
 		args     []string
@@ -150,6 +509,8 @@ func TestConfig(t *testing.T) {
 
 // TestParseEdgeCases verifies Parse function handles edge cases like empty marker strings correctly.
 func TestParseEdgeCases(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
 	t.Run("empty marker values", func(t *testing.T) {
 		// Reset pflag state
 		pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
@@ -163,3 +524,52 @@ func TestParseEdgeCases(t *testing.T) {
 		assert.Equal(t, "", cfg.MarkerSuffix)
 	})
 }
+
+// TestParseArgs verifies that ParseArgs parses flags and patterns without global state and reports invalid flags.
+func TestParseArgs(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg, patterns, err := ParseArgs([]string{"--format", "xml", "src", "--recursive", "docs"})
+	require.NoError(t, err)
+	assert.Equal(t, "xml", cfg.Format)
+	assert.True(t, cfg.Recursive)
+	assert.Equal(t, "<<<", cfg.MarkerPrefix)
+	assert.Equal(t, []string{"src", "docs"}, patterns)
+
+	_, _, err = ParseArgs([]string{"--no-such-flag"})
+	assert.Error(t, err)
+}
+
+// TestParseUnpackArgs verifies unpack flags, the stdin default and that extra arguments are rejected.
+func TestParseUnpackArgs(t *testing.T) {
+	cfg, input, err := ParseUnpackArgs([]string{"--root", "out", "--diff", "reply.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, UnpackConfig{Root: "out", Format: "auto", MarkerPrefix: "<<<", MarkerSuffix: ">>>", Diff: true}, cfg)
+	assert.Equal(t, "reply.txt", input)
+
+	_, input, err = ParseUnpackArgs(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "-", input)
+
+	_, _, err = ParseUnpackArgs([]string{"a", "b"})
+	assert.Error(t, err)
+}
+
+// TestParseApplyArgs verifies apply flags, the stdin default and that negative fuzz and extra arguments are rejected.
+func TestParseApplyArgs(t *testing.T) {
+	cfg, input, err := ParseApplyArgs([]string{"--root", "src", "--fuzz", "0", "--dry-run", "fix.patch"})
+	require.NoError(t, err)
+	assert.Equal(t, ApplyConfig{Root: "src", Fuzz: 0, DryRun: true}, cfg)
+	assert.Equal(t, "fix.patch", input)
+
+	cfg, input, err = ParseApplyArgs(nil)
+	require.NoError(t, err)
+	assert.Equal(t, ApplyConfig{Root: ".", Fuzz: 2}, cfg)
+	assert.Equal(t, "-", input)
+
+	_, _, err = ParseApplyArgs([]string{"--fuzz", "-1"})
+	assert.Error(t, err)
+
+	_, _, err = ParseApplyArgs([]string{"a", "b"})
+	assert.Error(t, err)
+}
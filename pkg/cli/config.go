@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the configuration file looked for at the repository root,
+// or in the working directory outside a repository.
+const ConfigFileName = ".txt2llm.yaml"
+
+// configFile holds option values keyed by flag name, plus "patterns", and
+// named profiles of the same form.
+type configFile struct {
+	values   map[string]any
+	profiles map[string]map[string]any
+}
+
+// configPaths returns the configuration files to load, lowest precedence
+// first: the user's config.yaml, then the repository's .txt2llm.yaml.
+func configPaths() []string {
+	var paths []string
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config")
+		}
+	}
+	if dir != "" {
+		paths = append(paths, filepath.Join(dir, "txt2llm", "config.yaml"))
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		paths = append(paths, filepath.Join(repoRoot(cwd), ConfigFileName))
+	}
+	return paths
+}
+
+// repoRoot returns the nearest ancestor of dir (inclusive) containing a .git
+// entry, or dir itself outside a repository.
+func repoRoot(dir string) string {
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
+
+// pathKeys are the options naming a file, which are relative to the
+// configuration file that sets them rather than to the working directory.
+var pathKeys = []string{"output", "redact-rules", "vocab"}
+
+// loadConfigFile reads the configuration file at path. A missing file yields
+// an empty configuration.
+func loadConfigFile(path string, fs *pflag.FlagSet) (configFile, error) {
+	cf := configFile{values: map[string]any{}, profiles: map[string]map[string]any{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cf, nil
+	}
+	if err != nil {
+		return cf, err
+	}
+
+	var raw struct {
+		Values   map[string]any            `yaml:",inline"`
+		Profiles map[string]map[string]any `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return cf, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := checkKeys(raw.Values, fs); err != nil {
+		return cf, fmt.Errorf("%s: %v", path, err)
+	}
+	for name, values := range raw.Profiles {
+		if err := checkKeys(values, fs); err != nil {
+			return cf, fmt.Errorf("%s: profile %s: %v", path, name, err)
+		}
+	}
+	resolvePaths(raw.Values, filepath.Dir(path))
+	for _, values := range raw.Profiles {
+		resolvePaths(values, filepath.Dir(path))
+	}
+	maps.Copy(cf.values, raw.Values)
+	maps.Copy(cf.profiles, raw.Profiles)
+	return cf, nil
+}
+
+// resolvePaths joins each relative file named in values to dir.
+func resolvePaths(values map[string]any, dir string) {
+	for _, key := range pathKeys {
+		if p, ok := values[key].(string); ok && p != "" && !filepath.IsAbs(p) {
+			values[key] = filepath.Join(dir, p)
+		}
+	}
+}
+
+// checkKeys reports the first key in values that is neither "patterns" nor a
+// flag that can be configured.
+func checkKeys(values map[string]any, fs *pflag.FlagSet) error {
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if key == "patterns" {
+			continue
+		}
+		if key == "profile" || key == "print-config" || fs.Lookup(key) == nil {
+			return fmt.Errorf("unknown option %q", key)
+		}
+	}
+	return nil
+}
+
+// applyConfigFiles sets every flag not given on the command line from the
+// configuration files, with the named profile, if any, layered over their
+// top-level values. It returns args, or the configured patterns if args is
+// empty.
+func applyConfigFiles(fs *pflag.FlagSet, args []string, profile string) ([]string, error) {
+	values := map[string]any{}
+	profiles := map[string]map[string]any{}
+	for _, path := range configPaths() {
+		cf, err := loadConfigFile(path, fs)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(values, cf.values)
+		for name, p := range cf.profiles {
+			if profiles[name] == nil {
+				profiles[name] = map[string]any{}
+			}
+			maps.Copy(profiles[name], p)
+		}
+	}
+	if profile != "" {
+		p, ok := profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q (configured: %s)", profile, profileNames(profiles))
+		}
+		maps.Copy(values, p)
+	}
+
+	patterns := args
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if key == "patterns" {
+			if len(args) == 0 {
+				list, err := stringList(values[key])
+				if err != nil {
+					return nil, fmt.Errorf("invalid patterns in configuration: %v", err)
+				}
+				patterns = list
+			}
+			continue
+		}
+		if fs.Changed(key) {
+			continue
+		}
+		if err := setFlag(fs, key, values[key]); err != nil {
+			return nil, fmt.Errorf("invalid %s in configuration: %v", key, err)
+		}
+	}
+	return patterns, nil
+}
+
+// setFlag sets the flag name from a configuration value. Repeatable flags
+// accept a list or a single value.
+func setFlag(fs *pflag.FlagSet, name string, value any) error {
+	if fs.Lookup(name).Value.Type() == "stringArray" {
+		list, err := stringList(value)
+		if err != nil {
+			return err
+		}
+		for _, v := range list {
+			if err := fs.Set(name, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, ok := value.([]any); ok {
+		return fmt.Errorf("want a single value, got a list")
+	}
+	return fs.Set(name, fmt.Sprint(value))
+}
+
+// stringList converts a scalar or list configuration value to strings.
+func stringList(value any) ([]string, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return []string{fmt.Sprint(value)}, nil
+	}
+	var out []string
+	for _, item := range items {
+		if _, nested := item.([]any); nested {
+			return nil, fmt.Errorf("want a list of strings")
+		}
+		out = append(out, fmt.Sprint(item))
+	}
+	return out, nil
+}
+
+// profileNames lists the configured profile names for error messages.
+func profileNames(profiles map[string]map[string]any) string {
+	if len(profiles) == 0 {
+		return "none"
+	}
+	return strings.Join(slices.Sorted(maps.Keys(profiles)), ", ")
+}
+
+// PrintConfig writes the effective configuration and patterns to w in the
+// configuration file format.
+func PrintConfig(w io.Writer, cfg Config, patterns []string) error {
+	out := struct {
+		Patterns []string `yaml:"patterns"`
+		Config   `yaml:",inline"`
+	}{patterns, cfg}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	return enc.Close()
+}
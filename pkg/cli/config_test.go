@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// writeConfigs creates a repository with a .txt2llm.yaml and a user config.yaml, and changes into a subdirectory of
+// the repository.
+func writeConfigs(t *testing.T, repoConfig, userConfig string) {
+	t.Helper()
+	repo := t.TempDir()
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ConfigFileName), []byte(repoConfig), 0644))
+	if userConfig != "" {
+		require.NoError(t, os.MkdirAll(filepath.Join(xdg, "txt2llm"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(xdg, "txt2llm", "config.yaml"), []byte(userConfig), 0644))
+	}
+
+	oldWd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(repo, "sub")))
+	t.Cleanup(func() { os.Chdir(oldWd) })
+}
+
+const testRepoConfig = `
+recursive: true
+exclude: ["*_test.go", vendor]
+marker-prefix: "[["
+vocab: tokens/cl100k_base.tiktoken
+profiles:
+  backend:
+    patterns: [cmd, pkg]
+    format: xml
+    max-tokens: 50000
+    output: /tmp/prompt.xml
+    redact-rules: rules.yaml
+  docs:
+    patterns: "**/*.md"
+    include: "*.md"
+`
+
+// TestParseArgsConfigFile verifies that configuration files and profiles supply defaults, with files they name
+// relative to them, flags override them, and the repository file takes precedence over the user file.
+func TestParseArgsConfigFile(t *testing.T) {
+	writeConfigs(t, testRepoConfig, "relative: true\nmarker-prefix: '<<'\nformat: markdown\nredact-rules: mine.yaml\n")
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	repo, xdg := filepath.Dir(cwd), os.Getenv("XDG_CONFIG_HOME")
+
+	tests := []struct {
+		name     string
+		args     []string
+		check    func(t *testing.T, cfg Config)
+		patterns []string
+	}{
+		{
+			name: "top-level values",
+			args: []string{"src"},
+			check: func(t *testing.T, cfg Config) {
+				assert.True(t, cfg.Recursive)
+				assert.True(t, cfg.Relative, "user config applies")
+				assert.Equal(t, "[[", cfg.MarkerPrefix, "repository config wins over user config")
+				assert.Equal(t, "markdown", cfg.Format)
+				assert.Equal(t, []string{"*_test.go", "vendor"}, cfg.Exclude)
+				assert.Equal(t, filepath.Join(repo, "tokens", "cl100k_base.tiktoken"), cfg.Vocab, "relative to the file")
+				assert.Equal(t, filepath.Join(xdg, "txt2llm", "mine.yaml"), cfg.RedactRules)
+			},
+			patterns: []string{"src"},
+		},
+		{
+			name: "profile",
+			args: []string{"-p", "backend"},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, "xml", cfg.Format)
+				assert.Equal(t, 50000, cfg.MaxTokens)
+				assert.True(t, cfg.Recursive)
+				assert.Equal(t, "/tmp/prompt.xml", cfg.Output, "absolute paths are kept")
+				assert.Equal(t, filepath.Join(repo, "rules.yaml"), cfg.RedactRules)
+			},
+			patterns: []string{"cmd", "pkg"},
+		},
+		{
+			name: "scalar lists",
+			args: []string{"--profile", "docs"},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, []string{"*.md"}, cfg.Include)
+			},
+			patterns: []string{"**/*.md"},
+		},
+		{
+			name: "flags override",
+			args: []string{"-p", "backend", "--format", "json", "--exclude", "gen", "--recursive=false", "--vocab", "v.tiktoken", "cmd"},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, "v.tiktoken", cfg.Vocab, "flags are relative to the working directory")
+				assert.Equal(t, "json", cfg.Format)
+				assert.Equal(t, []string{"gen"}, cfg.Exclude)
+				assert.False(t, cfg.Recursive)
+			},
+			patterns: []string{"cmd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, patterns, err := ParseArgs(tt.args)
+			require.NoError(t, err)
+			tt.check(t, cfg)
+			assert.Equal(t, tt.patterns, patterns)
+		})
+	}
+}
+
+// TestParseArgsConfigErrors verifies that unknown profiles, unknown keys and malformed values are reported.
+func TestParseArgsConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		args    []string
+		wantErr string
+	}{
+		{name: "unknown profile", config: testRepoConfig, args: []string{"-p", "frontend"}, wantErr: `unknown profile "frontend" (configured: backend, docs)`},
+		{name: "unknown key", config: "recursve: true\n", wantErr: `unknown option "recursve"`},
+		{name: "unknown key in profile", config: "profiles:\n  x:\n    profile: y\n", wantErr: `profile x: unknown option "profile"`},
+		{name: "list for a scalar", config: "format: [xml, json]\n", wantErr: "invalid format in configuration"},
+		{name: "bad type", config: "max-tokens: lots\n", wantErr: "invalid max-tokens in configuration"},
+		{name: "bad yaml", config: "recursive: [\n", wantErr: ConfigFileName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeConfigs(t, tt.config, "")
+			_, _, err := ParseArgs(tt.args)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+// TestPrintConfig verifies the effective configuration is printed as YAML that reads back to the same values.
+func TestPrintConfig(t *testing.T) {
+	writeConfigs(t, testRepoConfig, "")
+	cfg, patterns, err := ParseArgs([]string{"-p", "backend", "--print-config"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, PrintConfig(&buf, cfg, patterns))
+	assert.Contains(t, buf.String(), "patterns:\n  - cmd\n  - pkg\n")
+	assert.Contains(t, buf.String(), "format: xml\n")
+
+	var parsed Config
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &parsed))
+	assert.Empty(t, parsed.Include)
+	parsed.Include = cfg.Include
+	cfg.Profile, cfg.PrintConfig = "", false
+	assert.Equal(t, cfg, parsed)
+}
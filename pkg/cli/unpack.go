@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+// UnpackConfig holds parsed flags for the unpack subcommand.
+type UnpackConfig struct {
+	Root         string
+	Format       string
+	MarkerPrefix string
+	MarkerSuffix string
+	DryRun       bool
+	Diff         bool
+}
+
+// ParseUnpackArgs parses the arguments following "unpack", returning the
+// configuration and the input file, which is "-" for stdin.
+func ParseUnpackArgs(args []string) (UnpackConfig, string, error) {
+	var cfg UnpackConfig
+	fs := pflag.NewFlagSet("txt2llm unpack", pflag.ContinueOnError)
+	fs.StringVar(&cfg.Root, "root", ".", "Directory to write files under; paths may not escape it")
+	fs.StringVar(&cfg.Format, "format", "auto", "Input format: auto, markers, xml, markdown, json or jsonl")
+	fs.StringVar(&cfg.MarkerPrefix, "marker-prefix", "<<<", "Prefix for start/end marker lines")
+	fs.StringVar(&cfg.MarkerSuffix, "marker-suffix", ">>>", "Suffix for start/end marker lines")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "List the files that would be written, and whether each is new, modified or unchanged")
+	fs.BoolVar(&cfg.Diff, "diff", false, "Print a unified diff of what would change instead of writing")
+	if err := fs.Parse(args); err != nil {
+		return UnpackConfig{}, "", err
+	}
+	switch fs.NArg() {
+	case 0:
+		return cfg, "-", nil
+	case 1:
+		return cfg, fs.Arg(0), nil
+	}
+	return UnpackConfig{}, "", fmt.Errorf("unpack takes at most one input file, got %d", fs.NArg())
+}
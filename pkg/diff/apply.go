@@ -0,0 +1,161 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HunkResult describes how one hunk was applied.
+type HunkResult struct {
+	Applied bool
+	// Offset is how many lines from its stated position the hunk matched.
+	Offset int
+	// Fuzz is the number of context lines ignored at each end to match.
+	Fuzz int
+	// Whitespace reports that the match ignored whitespace differences.
+	Whitespace bool
+}
+
+// String describes the result for a report.
+func (r HunkResult) String() string {
+	if !r.Applied {
+		return "FAILED"
+	}
+	var notes []string
+	if r.Offset != 0 {
+		notes = append(notes, fmt.Sprintf("offset %+d", r.Offset))
+	}
+	if r.Fuzz > 0 {
+		notes = append(notes, fmt.Sprintf("fuzz %d", r.Fuzz))
+	}
+	if r.Whitespace {
+		notes = append(notes, "ignoring whitespace")
+	}
+	if len(notes) == 0 {
+		return "applied"
+	}
+	return "applied (" + strings.Join(notes, ", ") + ")"
+}
+
+// Apply applies hunks to content in order, returning the patched content and
+// a result for each hunk. A hunk that does not match exactly is searched for
+// nearby, then with whitespace ignored, then with up to fuzz context lines
+// dropped from each end. Hunks that still do not match are left out.
+func Apply(content []byte, hunks []Hunk, fuzz int) ([]byte, []HunkResult) {
+	file := SplitLines(string(content))
+	var out []string
+	results := make([]HunkResult, len(hunks))
+	done := 0   // lines of file already copied or replaced
+	offset := 0 // offset of the last applied hunk, expected to carry on
+	for i, h := range hunks {
+		pos, res, lines := locate(file, done, h, offset, fuzz)
+		results[i] = res
+		if !res.Applied {
+			continue
+		}
+		out = append(out, file[done:pos]...)
+		var n int
+		out, n = replace(out, file[pos:], lines)
+		done = pos + n
+		offset = res.Offset
+	}
+	out = append(out, file[done:]...)
+	return []byte(strings.Join(out, "")), results
+}
+
+// locate finds where h applies in file at or after from, trying exact
+// matches, then whitespace-insensitive ones, with increasing fuzz. It returns
+// the position, the result and the hunk lines that matched.
+func locate(file []string, from int, h Hunk, offset, fuzz int) (int, HunkResult, []string) {
+	for f := 0; f <= fuzz; f++ {
+		lines, dropped, ok := trimContext(h.Lines, f)
+		if !ok {
+			break
+		}
+		var old []string
+		for _, l := range lines {
+			if l[0] != '+' {
+				old = append(old, l[1:])
+			}
+		}
+		expected := max(h.OldStart-1, 0) + dropped + offset
+		if len(old) == 0 && h.OldStart > 0 {
+			// A pure insertion's start is the line it follows.
+			expected++
+		}
+		for _, ws := range []bool{false, true} {
+			if pos, ok := search(file, from, old, expected, ws); ok {
+				return pos, HunkResult{Applied: true, Offset: pos - expected + offset, Fuzz: f, Whitespace: ws}, lines
+			}
+		}
+	}
+	return 0, HunkResult{}, nil
+}
+
+// trimContext drops up to n context lines from each end of lines, returning
+// the remaining lines and how many were dropped from the start. It reports
+// false once no more context can be dropped at this level.
+func trimContext(lines []string, n int) ([]string, int, bool) {
+	lead, trail := 0, 0
+	for lead < n && lead < len(lines) && lines[lead][0] == ' ' {
+		lead++
+	}
+	for trail < n && trail < len(lines)-lead && lines[len(lines)-1-trail][0] == ' ' {
+		trail++
+	}
+	if n > 0 && lead < n && trail < n {
+		return nil, 0, false
+	}
+	return lines[lead : len(lines)-trail], lead, true
+}
+
+// search finds old in file at or after from, trying positions in order of
+// distance from expected.
+func search(file []string, from int, old []string, expected int, ws bool) (int, bool) {
+	last := len(file) - len(old)
+	if last < from {
+		return 0, false
+	}
+	expected = min(max(expected, from), last)
+	for d := 0; expected-d >= from || expected+d <= last; d++ {
+		for _, pos := range []int{expected - d, expected + d} {
+			if pos >= from && pos <= last && matchAt(file[pos:], old, ws) {
+				return pos, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// matchAt reports whether file starts with old.
+func matchAt(file, old []string, ws bool) bool {
+	for i, l := range old {
+		if ws {
+			if strings.Join(strings.Fields(file[i]), " ") != strings.Join(strings.Fields(l), " ") {
+				return false
+			}
+		} else if file[i] != l {
+			return false
+		}
+	}
+	return true
+}
+
+// replace appends the result of applying hunk lines to the start of file to
+// out, keeping the file's own version of context lines, and returns it with
+// the number of file lines consumed.
+func replace(out, file, lines []string) ([]string, int) {
+	n := 0
+	for _, l := range lines {
+		switch l[0] {
+		case ' ':
+			out = append(out, file[n])
+			n++
+		case '-':
+			n++
+		case '+':
+			out = append(out, l[1:])
+		}
+	}
+	return out, n
+}
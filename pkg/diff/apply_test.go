@@ -0,0 +1,119 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestApply verifies exact, offset, whitespace-insensitive and fuzzy matches, and that failed hunks are left out.
+func TestApply(t *testing.T) {
+	file := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	patch := func(header, body string) []Hunk {
+		return Extract("--- a/f\n+++ b/f\n" + header + "\n" + body)[0].Hunks
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		hunks    []Hunk
+		fuzz     int
+		expected string
+		results  []HunkResult
+	}{
+		{
+			name:     "exact",
+			content:  file,
+			hunks:    patch("@@ -2,3 +2,3 @@", " two\n-three\n+THREE\n four\n"),
+			expected: strings.Replace(file, "three", "THREE", 1),
+			results:  []HunkResult{{Applied: true}},
+		},
+		{
+			name:     "offset",
+			content:  "zero\n" + file,
+			hunks:    patch("@@ -2,3 +2,3 @@", " two\n-three\n+THREE\n four\n"),
+			expected: "zero\n" + strings.Replace(file, "three", "THREE", 1),
+			results:  []HunkResult{{Applied: true, Offset: 1}},
+		},
+		{
+			name:     "whitespace",
+			content:  strings.Replace(file, "two", "  two  ", 1),
+			hunks:    patch("@@ -2,3 +2,3 @@", " two\n-three\n+THREE\n four\n"),
+			expected: strings.Replace(strings.Replace(file, "two", "  two  ", 1), "three", "THREE", 1),
+			results:  []HunkResult{{Applied: true, Whitespace: true}},
+		},
+		{
+			name:     "fuzz",
+			content:  file,
+			hunks:    patch("@@ -2,3 +2,3 @@", " TWO\n-three\n+THREE\n four\n"),
+			fuzz:     1,
+			expected: strings.Replace(file, "three", "THREE", 1),
+			results:  []HunkResult{{Applied: true, Fuzz: 1}},
+		},
+		{
+			name:     "no fuzz allowed",
+			content:  file,
+			hunks:    patch("@@ -2,3 +2,3 @@", " TWO\n-three\n+THREE\n four\n"),
+			expected: file,
+			results:  []HunkResult{{}},
+		},
+		{
+			name:    "later hunk follows earlier offset",
+			content: "a\nb\n" + file,
+			hunks: append(patch("@@ -1,2 +1,2 @@", "-one\n+ONE\n two\n"),
+				patch("@@ -9,2 +9,2 @@", " nine\n-ten\n+TEN\n")...),
+			expected: "a\nb\n" + strings.Replace(strings.Replace(file, "one", "ONE", 1), "ten", "TEN", 1),
+			results:  []HunkResult{{Applied: true, Offset: 2}, {Applied: true, Offset: 2}},
+		},
+		{
+			name:    "one hunk fails",
+			content: file,
+			hunks: append(patch("@@ -1 +1 @@", "-missing\n+x\n"),
+				patch("@@ -10 +10 @@", "-ten\n+TEN\n")...),
+			expected: strings.Replace(file, "ten", "TEN", 1),
+			results:  []HunkResult{{}, {Applied: true}},
+		},
+		{
+			name:     "new file",
+			content:  "",
+			hunks:    patch("@@ -0,0 +1,2 @@", "+a\n+b\n"),
+			expected: "a\nb\n",
+			results:  []HunkResult{{Applied: true}},
+		},
+		{
+			name:     "append at end",
+			content:  "a\n",
+			hunks:    patch("@@ -1 +1,2 @@", " a\n+b\n"),
+			expected: "a\nb\n",
+			results:  []HunkResult{{Applied: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, results := Apply([]byte(tt.content), tt.hunks, tt.fuzz)
+			assert.Equal(t, tt.expected, string(got))
+			assert.Equal(t, tt.results, results)
+		})
+	}
+}
+
+// TestApplyRoundTrip verifies that applying a generated diff reproduces the new content.
+func TestApplyRoundTrip(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nL\nm\nn"
+	patches := Extract(Unified("a/x", "b/x", []byte(old), []byte(new)))
+	got, results := Apply([]byte(old), patches[0].Hunks, 0)
+	assert.Equal(t, new, string(got))
+	for _, r := range results {
+		assert.Equal(t, "applied", r.String())
+	}
+}
+
+// TestHunkResultString verifies the per-hunk report text.
+func TestHunkResultString(t *testing.T) {
+	assert.Equal(t, "FAILED", HunkResult{}.String())
+	assert.Equal(t, "applied", HunkResult{Applied: true}.String())
+	assert.Equal(t, "applied (offset -3, fuzz 1, ignoring whitespace)", HunkResult{Applied: true, Offset: -3, Fuzz: 1, Whitespace: true}.String())
+}
@@ -0,0 +1,296 @@
+// Package diff computes line-based unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines Unified shows around each
+// change.
+const DefaultContext = 3
+
+// op is one line of an edit script: ' ' keeps a line, '-' deletes a line of
+// the old text and '+' inserts a line of the new text.
+type op struct {
+	kind byte
+	line string
+}
+
+// Unified returns a unified diff turning old into new, labelled with oldName
+// and newName, or "" if they are equal.
+func Unified(oldName, newName string, old, new []byte) string {
+	return UnifiedContext(oldName, newName, old, new, DefaultContext)
+}
+
+// UnifiedContext is like Unified with context unchanged lines around each
+// change instead of DefaultContext.
+func UnifiedContext(oldName, newName string, old, new []byte, context int) string {
+	ops := edits(SplitLines(string(old)), SplitLines(string(new)))
+
+	var b strings.Builder
+	for _, h := range hunks(ops, max(context, 0)) {
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		b.WriteString(h)
+	}
+	return b.String()
+}
+
+// SplitLines splits s into lines, each keeping its "\n" terminator; the last
+// line lacks one if s does not end with a newline.
+func SplitLines(s string) []string {
+	var lines []string
+	for s != "" {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
+
+// maxCost caps how far the search for the middle of an edit script goes, in
+// edits from each end, before a part of the texts is treated as rewritten.
+const maxCost = 4096
+
+// edits returns an edit script from a to b using the linear-space form of
+// Myers' algorithm, which finds the middle of a shortest script and recurses
+// on either side of it. The script is shortest unless a part of the texts
+// needs more than 2*maxCost edits, in which case that part is deleted and
+// inserted whole so very different texts still diff quickly.
+func edits(a, b []string) []op {
+	ids := map[string]int{}
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, l := range lines {
+			id, ok := ids[l]
+			if !ok {
+				id = len(ids)
+				ids[l] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	s := script{a: a, b: b, ai: intern(a), bi: intern(b)}
+	s.compare(0, len(a), 0, len(b))
+	return deletesFirst(s.ops)
+}
+
+// script builds the edit script from a to b, comparing lines by the ids in
+// ai and bi.
+type script struct {
+	a, b   []string
+	ai, bi []int
+	ops    []op
+}
+
+// compare appends the edits turning a[a0:a1] into b[b0:b1].
+func (s *script) compare(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && s.ai[a0] == s.bi[b0] {
+		s.ops = append(s.ops, op{' ', s.a[a0]})
+		a0++
+		b0++
+	}
+	common := 0
+	for a1 > a0 && b1 > b0 && s.ai[a1-1] == s.bi[b1-1] {
+		a1--
+		b1--
+		common++
+	}
+	if x, y, ok := s.middle(a0, a1, b0, b1); ok {
+		s.compare(a0, x, b0, y)
+		s.compare(x, a1, y, b1)
+	} else {
+		for _, l := range s.a[a0:a1] {
+			s.ops = append(s.ops, op{'-', l})
+		}
+		for _, l := range s.b[b0:b1] {
+			s.ops = append(s.ops, op{'+', l})
+		}
+	}
+	for _, l := range s.a[a1 : a1+common] {
+		s.ops = append(s.ops, op{' ', l})
+	}
+}
+
+// middle searches forwards from the start and backwards from the end of
+// a[a0:a1] and b[b0:b1], which differ in their first and last lines, until
+// the two paths meet. It returns the point where they meet, from which both
+// halves can be compared separately, or ok false if either range is empty,
+// nothing matches or the paths have not met within maxCost edits.
+func (s *script) middle(a0, a1, b0, b1 int) (x, y int, ok bool) {
+	n, m := a1-a0, b1-b0
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := min((n+m+1)/2, maxCost)
+	offset := maxD
+	fwd := make([]int, 2*maxD+2)
+	bwd := make([]int, 2*maxD+2)
+	for i := range fwd {
+		fwd[i], bwd[i] = -1, -1
+	}
+	fwd[offset+1], bwd[offset+1] = 0, 0
+	delta := n - m
+	odd := delta%2 != 0
+	// Diagonals that have run off the edge are trimmed from each end.
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for d := range maxD {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			i := offset + k
+			x := next(fwd, i, k, d)
+			y := x - k
+			for x < n && y < m && s.ai[a0+x] == s.bi[b0+y] {
+				x++
+				y++
+			}
+			fwd[i] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if j := offset + delta - k; j >= 0 && j < len(bwd) && bwd[j] != -1 && x >= n-bwd[j] {
+					return a0 + x, b0 + y, true
+				}
+			}
+		}
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			i := offset + k
+			x := next(bwd, i, k, d)
+			y := x - k
+			for x < n && y < m && s.ai[a1-x-1] == s.bi[b1-y-1] {
+				x++
+				y++
+			}
+			bwd[i] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				if j := offset + delta - k; j >= 0 && j < len(fwd) && fwd[j] != -1 && fwd[j] >= n-x {
+					fx := fwd[j]
+					return a0 + fx, b0 + fx - (j - offset), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// next returns how far along diagonal k a path of d edits reaches before
+// following matching lines, given the furthest points v reached with d-1.
+func next(v []int, i, k, d int) int {
+	if k == -d || k != d && v[i-1] < v[i+1] {
+		return v[i+1]
+	}
+	return v[i-1] + 1
+}
+
+// deletesFirst puts the deletions in each run of changes before its
+// insertions, the order unified diffs show them in.
+func deletesFirst(ops []op) []op {
+	out := make([]op, 0, len(ops))
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			out = append(out, ops[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].kind != ' ' {
+			j++
+		}
+		for _, kind := range []byte{'-', '+'} {
+			for _, o := range ops[i:j] {
+				if o.kind == kind {
+					out = append(out, o)
+				}
+			}
+		}
+		i = j
+	}
+	return out
+}
+
+// hunks groups ops into unified diff hunks with context lines around each
+// change, merging changes whose context would overlap.
+func hunks(ops []op, context int) []string {
+	var out []string
+	// oldLine and newLine number the lines of ops[counted], so each op is
+	// counted once however many hunks there are.
+	oldLine, newLine, counted := 1, 1, 0
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(0, i-context)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end = min(len(ops), end+context)
+		for _, o := range ops[counted:start] {
+			if o.kind != '+' {
+				oldLine++
+			}
+			if o.kind != '-' {
+				newLine++
+			}
+		}
+		counted = start
+		out = append(out, hunk(ops[start:end], oldLine, newLine))
+		i = end
+	}
+	return out
+}
+
+// hunk formats ops as one hunk whose first lines are oldLine in the old
+// text and newLine in the new.
+func hunk(ops []op, oldLine, newLine int) string {
+	var body strings.Builder
+	oldCount, newCount := 0, 0
+	for _, o := range ops {
+		if o.kind != '+' {
+			oldCount++
+		}
+		if o.kind != '-' {
+			newCount++
+		}
+		body.WriteByte(o.kind)
+		body.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			body.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	// An empty range is numbered by the line before it.
+	if oldCount == 0 {
+		oldLine--
+	}
+	if newCount == 0 {
+		newLine--
+	}
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", span(oldLine, oldCount), span(newLine, newCount), body.String())
+}
+
+// span formats a hunk range, omitting a count of one.
+func span(line, count int) string {
+	if count == 1 {
+		return fmt.Sprint(line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
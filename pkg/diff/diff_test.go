@@ -0,0 +1,107 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUnified verifies hunk headers, context lines, merged nearby changes and missing final newlines.
+func TestUnified(t *testing.T) {
+	lines := func(n int, change map[int]string) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			if s, ok := change[i]; ok {
+				b.WriteString(s)
+				continue
+			}
+			b.WriteString(string(rune('a'+i-1)) + "\n")
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name     string
+		old, new string
+		expected string
+	}{
+		{name: "equal", old: "a\nb\n", new: "a\nb\n", expected: ""},
+		{
+			name:     "new file",
+			old:      "",
+			new:      "a\nb\n",
+			expected: "--- x\n+++ y\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "change in the middle",
+			old:      lines(10, nil),
+			new:      lines(10, map[int]string{5: "E\n"}),
+			expected: "--- x\n+++ y\n@@ -2,7 +2,7 @@\n b\n c\n d\n-e\n+E\n f\n g\n h\n",
+		},
+		{
+			name: "distant changes make two hunks",
+			old:  lines(12, nil),
+			new:  lines(12, map[int]string{1: "A\n", 12: "L\n"}),
+			expected: "--- x\n+++ y\n@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n d\n" +
+				"@@ -9,4 +9,4 @@\n i\n j\n k\n-l\n+L\n",
+		},
+		{
+			name:     "missing newline",
+			old:      "a\nb",
+			new:      "a\nb\n",
+			expected: "--- x\n+++ y\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Unified("x", "y", []byte(tt.old), []byte(tt.new)))
+		})
+	}
+}
+
+// TestSplitLines verifies lines keep their terminators and a final partial line is kept.
+func TestSplitLines(t *testing.T) {
+	assert.Nil(t, SplitLines(""))
+	assert.Equal(t, []string{"a\n", "b"}, SplitLines("a\nb"))
+	assert.Equal(t, []string{"a\n", "\n"}, SplitLines("a\n\n"))
+}
+
+// TestUnifiedContext verifies that the number of context lines is configurable, that changes with no context
+// between them stay in separate hunks, and that each hunk is numbered after the lines added or removed before it.
+func TestUnifiedContext(t *testing.T) {
+	old := "a\nb\nc\nd\ne\n"
+	new := "A\nb\nc\nd\nE\n"
+	assert.Equal(t, "--- x\n+++ y\n@@ -1 +1 @@\n-a\n+A\n@@ -5 +5 @@\n-e\n+E\n", UnifiedContext("x", "y", []byte(old), []byte(new), 0))
+	assert.Equal(t, "--- x\n+++ y\n@@ -1,5 +1,5 @@\n-a\n+A\n b\n c\n d\n-e\n+E\n", UnifiedContext("x", "y", []byte(old), []byte(new), 2))
+	assert.Equal(t, Unified("x", "y", []byte(old), []byte(new)), UnifiedContext("x", "y", []byte(old), []byte(new), DefaultContext))
+
+	shifted := "a\nX\nY\nb\nc\ne\n"
+	assert.Equal(t, "--- x\n+++ y\n@@ -1,0 +2,2 @@\n+X\n+Y\n@@ -4 +5,0 @@\n-d\n",
+		UnifiedContext("x", "y", []byte(old), []byte(shifted), 0), "later hunks are numbered after earlier insertions")
+}
+
+// TestUnifiedLarge verifies that texts too different to search fully still give a diff that applies, and that a
+// small change in a large file gives a small diff.
+func TestUnifiedLarge(t *testing.T) {
+	var old, new strings.Builder
+	for i := range 3 * maxCost {
+		fmt.Fprintf(&old, "line %d\n", i)
+		if i%7 == 0 {
+			fmt.Fprintf(&new, "line %d\n", i)
+		} else {
+			fmt.Fprintf(&new, "changed %d\n", i)
+		}
+	}
+	patches := Extract(Unified("a/x", "b/x", []byte(old.String()), []byte(new.String())))
+	require.Len(t, patches, 1)
+	got, _ := Apply([]byte(old.String()), patches[0].Hunks, 0)
+	assert.Equal(t, new.String(), string(got))
+
+	changed := strings.Replace(old.String(), "line 5000\n", "line five thousand\n", 1)
+	assert.Equal(t, "--- x\n+++ y\n@@ -5001 +5001 @@\n-line 5000\n+line five thousand\n",
+		UnifiedContext("x", "y", []byte(old.String()), []byte(changed), 0))
+}
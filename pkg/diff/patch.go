@@ -0,0 +1,159 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// devNull is the path a unified diff uses for a missing side.
+const devNull = "/dev/null"
+
+// FilePatch is the unified diff for one file.
+type FilePatch struct {
+	OldPath string
+	NewPath string
+	Hunks   []Hunk
+}
+
+// Path returns the path the patch applies to.
+func (p FilePatch) Path() string {
+	if p.NewPath == devNull {
+		return p.OldPath
+	}
+	return p.NewPath
+}
+
+// IsNew reports whether the patch creates its file.
+func (p FilePatch) IsNew() bool { return p.OldPath == devNull }
+
+// IsDelete reports whether the patch deletes its file.
+func (p FilePatch) IsDelete() bool { return p.NewPath == devNull }
+
+// Hunk is one @@ section of a unified diff. Each line keeps its ' ', '-' or
+// '+' prefix and its "\n" terminator, which is dropped for a line marked
+// "\ No newline at end of file".
+type Hunk struct {
+	OldStart int
+	NewStart int
+	Lines    []string
+}
+
+// String formats h as a unified diff hunk, with counts matching its lines.
+func (h Hunk) String() string {
+	oldCount, newCount := 0, 0
+	var body strings.Builder
+	for _, l := range h.Lines {
+		if l[0] != '+' {
+			oldCount++
+		}
+		if l[0] != '-' {
+			newCount++
+		}
+		body.WriteString(l)
+		if !strings.HasSuffix(l, "\n") {
+			body.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", span(h.OldStart, oldCount), span(h.NewStart, newCount), body.String())
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Extract finds every unified diff in text, which may be wrapped in prose,
+// Markdown code fences or bundle markers. Hunks are read leniently: line
+// counts in @@ headers are used when they fit, and hunk lines beyond them
+// are still taken, since models often miscount. Leading "a/" and "b/" path
+// prefixes are removed.
+func Extract(text string) []FilePatch {
+	lines := SplitLines(text)
+	var patches []FilePatch
+	for i := 0; i < len(lines); {
+		if !isFileHeader(lines, i) {
+			i++
+			continue
+		}
+		p := FilePatch{OldPath: headerPath(lines[i], "a/"), NewPath: headerPath(lines[i+1], "b/")}
+		i += 2
+		for i < len(lines) && hunkHeader.MatchString(lines[i]) {
+			h, n := parseHunk(lines[i:])
+			p.Hunks = append(p.Hunks, h)
+			i += n
+		}
+		if len(p.Hunks) > 0 {
+			patches = append(patches, p)
+		}
+	}
+	return patches
+}
+
+// isFileHeader reports whether lines[i] starts a "--- old" / "+++ new" pair.
+func isFileHeader(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.HasPrefix(lines[i], "--- ") && strings.HasPrefix(lines[i+1], "+++ ")
+}
+
+// headerPath extracts the path from a ---/+++ line, dropping any timestamp
+// and the given a/ or b/ prefix.
+func headerPath(line, prefix string) string {
+	p := strings.TrimRight(line[4:], "\r\n")
+	if tab := strings.IndexByte(p, '\t'); tab >= 0 {
+		p = p[:tab]
+	}
+	p = strings.Trim(strings.TrimSpace(p), `"`)
+	if p == devNull {
+		return p
+	}
+	return strings.TrimPrefix(p, prefix)
+}
+
+// parseHunk parses the hunk starting at lines[0], returning it and the number
+// of lines consumed.
+func parseHunk(lines []string) (Hunk, int) {
+	m := hunkHeader.FindStringSubmatch(lines[0])
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	h := Hunk{}
+	h.OldStart, _ = strconv.Atoi(m[1])
+	h.NewStart, _ = strconv.Atoi(m[3])
+	oldLeft, newLeft := count(m[2]), count(m[4])
+
+	i := 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		counted := oldLeft > 0 || newLeft > 0
+		switch {
+		case strings.HasPrefix(line, `\`):
+			if n := len(h.Lines); n > 0 {
+				h.Lines[n-1] = strings.TrimSuffix(h.Lines[n-1], "\n")
+			}
+			if !counted {
+				return h, i + 1
+			}
+			continue
+		case isFileHeader(lines, i) || hunkHeader.MatchString(line):
+			return h, i
+		case line == "\n" || line == "\r\n":
+			// A blank context line that lost its leading space.
+			if !counted {
+				return h, i
+			}
+			line = " " + line
+		case line[0] != ' ' && line[0] != '-' && line[0] != '+':
+			return h, i
+		}
+		if line[0] != '+' {
+			oldLeft--
+		}
+		if line[0] != '-' {
+			newLeft--
+		}
+		h.Lines = append(h.Lines, line)
+	}
+	return h, i
+}
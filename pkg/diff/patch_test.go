@@ -0,0 +1,46 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExtract verifies that diffs are found in surrounding prose and fences, with paths stripped and miscounted
+// hunks read in full.
+func TestExtract(t *testing.T) {
+	reply := "Here is the fix:\n\n```diff\n" +
+		"diff --git a/main.go b/main.go\n" +
+		"--- a/main.go\t2024-01-01 00:00:00\n+++ b/main.go\n" +
+		"@@ -1,2 +1,2 @@\n package main\n-var x = 1\n+var x = 2\n" +
+		"@@ -10 +10,2 @@\n-}\n+}\n+\n" +
+		"```\n\nAnd a new file:\n\n" +
+		"--- /dev/null\n+++ b/docs/new.md\n@@ -0,0 +1 @@\n+# New\n\\ No newline at end of file\n" +
+		"- this bullet is prose\n\n" +
+		"--- a/old.txt\n+++ /dev/null\n@@ -1,5 +0,0 @@\n-gone\n\n-also gone\n```\n"
+
+	patches := Extract(reply)
+	require.Len(t, patches, 3)
+
+	assert.Equal(t, "main.go", patches[0].Path())
+	require.Len(t, patches[0].Hunks, 2)
+	assert.Equal(t, Hunk{OldStart: 1, NewStart: 1, Lines: []string{" package main\n", "-var x = 1\n", "+var x = 2\n"}}, patches[0].Hunks[0])
+	assert.Equal(t, []string{"-}\n", "+}\n", "+\n"}, patches[0].Hunks[1].Lines)
+
+	assert.True(t, patches[1].IsNew())
+	assert.Equal(t, "docs/new.md", patches[1].Path())
+	assert.Equal(t, []string{"+# New"}, patches[1].Hunks[0].Lines, "prose after the hunk is not part of it")
+
+	assert.True(t, patches[2].IsDelete())
+	assert.Equal(t, "old.txt", patches[2].Path())
+	assert.Equal(t, []string{"-gone\n", " \n", "-also gone\n"}, patches[2].Hunks[0].Lines, "a bare blank line is context")
+
+	assert.Empty(t, Extract("--- not a diff\nsome text\n"))
+}
+
+// TestHunkString verifies that hunks print with counts recomputed from their lines.
+func TestHunkString(t *testing.T) {
+	h := Hunk{OldStart: 3, NewStart: 3, Lines: []string{" a\n", "-b\n", "+B\n", "+C"}}
+	assert.Equal(t, "@@ -3,2 +3,3 @@\n a\n-b\n+B\n+C\n\\ No newline at end of file\n", h.String())
+}
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// AtomicFile writes to a temporary file beside its destination and renames it
+// into place on Commit, so the destination never holds a partial bundle.
+type AtomicFile struct {
+	f    *os.File
+	path string
+	n    int64
+}
+
+// CreateAtomic starts writing a new version of the file at path.
+func CreateAtomic(path string) (*AtomicFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), tempPattern(path))
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{f: f, path: path}, nil
+}
+
+// tempPattern is the os.CreateTemp pattern for temporary files beside path.
+func tempPattern(path string) string {
+	return "." + filepath.Base(path) + ".tmp-*"
+}
+
+// IsTemp reports whether file is a temporary file CreateAtomic makes, or
+// left behind by an interrupted run, for path.
+func IsTemp(file, path string) bool {
+	if filepath.Dir(file) != filepath.Dir(path) {
+		return false
+	}
+	ok, _ := filepath.Match(tempPattern(path), filepath.Base(file))
+	return ok
+}
+
+// Write writes to the temporary file.
+func (a *AtomicFile) Write(p []byte) (int, error) {
+	n, err := a.f.Write(p)
+	a.n += int64(n)
+	return n, err
+}
+
+// Written returns the number of bytes written so far.
+func (a *AtomicFile) Written() int64 {
+	return a.n
+}
+
+// Commit flushes the temporary file and renames it over the destination,
+// keeping the destination's permissions if it already exists.
+func (a *AtomicFile) Commit() error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(a.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := a.f.Chmod(mode); err != nil {
+		a.Abort()
+		return err
+	}
+	if err := a.f.Sync(); err != nil {
+		a.Abort()
+		return err
+	}
+	if err := a.f.Close(); err != nil {
+		_ = os.Remove(a.f.Name())
+		return err
+	}
+	if err := os.Rename(a.f.Name(), a.path); err != nil {
+		_ = os.Remove(a.f.Name())
+		return err
+	}
+	return nil
+}
+
+// Abort discards the temporary file, leaving the destination untouched.
+func (a *AtomicFile) Abort() {
+	_ = a.f.Close()
+	_ = os.Remove(a.f.Name())
+}
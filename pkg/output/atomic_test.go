@@ -0,0 +1,80 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAtomicFile verifies that content only appears at the destination after Commit, and never after Abort.
+func TestAtomicFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	dest := filepath.Join(tmpDir, "bundle.txt")
+	require.NoError(t, os.WriteFile(dest, []byte("old"), 0600))
+
+	t.Run("commit replaces destination", func(t *testing.T) {
+		f, err := CreateAtomic(dest)
+		require.NoError(t, err)
+		_, err = f.Write([]byte("new content"))
+		require.NoError(t, err)
+
+		data, _ := os.ReadFile(dest)
+		assert.Equal(t, "old", string(data), "destination must not change before Commit")
+
+		require.NoError(t, f.Commit())
+		assert.Equal(t, int64(11), f.Written())
+		data, _ = os.ReadFile(dest)
+		assert.Equal(t, "new content", string(data))
+
+		info, err := os.Stat(dest)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "existing permissions should be kept")
+	})
+
+	t.Run("abort leaves destination untouched", func(t *testing.T) {
+		f, err := CreateAtomic(dest)
+		require.NoError(t, err)
+		_, _ = f.Write([]byte("discarded"))
+		f.Abort()
+
+		data, _ := os.ReadFile(dest)
+		assert.Equal(t, "new content", string(data))
+		entries, _ := os.ReadDir(tmpDir)
+		assert.Len(t, entries, 1, "temporary file should be removed")
+	})
+
+	t.Run("temporary files are recognised", func(t *testing.T) {
+		f, err := CreateAtomic(dest)
+		require.NoError(t, err)
+		defer f.Abort()
+		assert.True(t, IsTemp(f.f.Name(), dest))
+		assert.False(t, IsTemp(dest, dest))
+		assert.False(t, IsTemp(filepath.Join(tmpDir, "sub", ".bundle.txt.tmp-1"), dest))
+		assert.False(t, IsTemp(filepath.Join(tmpDir, ".other.txt.tmp-1"), dest))
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := CreateAtomic(filepath.Join(tmpDir, "missing", "bundle.txt"))
+		assert.Error(t, err)
+	})
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+// TestRenderWriteError verifies that Render reports errors writing to its destination.
+func TestRenderWriteError(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "a.txt")
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0644))
+
+	_, err := Render(context.Background(), failingWriter{}, []string{file}, []string{"a.txt"}, Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>"})
+	assert.EqualError(t, err, "disk full")
+}
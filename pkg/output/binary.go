@@ -0,0 +1,130 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// BinaryMode selects how files detected as binary are emitted.
+type BinaryMode int
+
+const (
+	// BinarySkip omits binary files, noting each one on stderr.
+	BinarySkip BinaryMode = iota
+	// BinaryPlaceholder emits a section containing a one-line description
+	// instead of the file's contents.
+	BinaryPlaceholder
+	// BinaryInclude emits binary files verbatim.
+	BinaryInclude
+)
+
+// ParseBinaryMode converts a --binary flag value to a BinaryMode.
+func ParseBinaryMode(s string) (BinaryMode, error) {
+	switch strings.ToLower(s) {
+	case "skip":
+		return BinarySkip, nil
+	case "placeholder":
+		return BinaryPlaceholder, nil
+	case "include":
+		return BinaryInclude, nil
+	}
+	return BinarySkip, fmt.Errorf("invalid binary mode %q (want skip, placeholder or include)", s)
+}
+
+// sniffLen is how much of a file is inspected when classifying it.
+const sniffLen = 8000
+
+// magic maps leading bytes of common binary formats that http.DetectContentType
+// does not recognise to their MIME types.
+var magic = []struct {
+	prefix []byte
+	mime   string
+}{
+	{[]byte("\x7fELF"), "application/x-elf"},
+	{[]byte("\xfe\xed\xfa\xce"), "application/x-mach-binary"},
+	{[]byte("\xfe\xed\xfa\xcf"), "application/x-mach-binary"},
+	{[]byte("\xce\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\xca\xfe\xba\xbe"), "application/java-vm"},
+	{[]byte("SQLite format 3\x00"), "application/vnd.sqlite3"},
+	{[]byte("\x28\xb5\x2f\xfd"), "application/zstd"},
+	{[]byte("\xfd7zXZ\x00"), "application/x-xz"},
+	{[]byte("7z\xbc\xaf\x27\x1c"), "application/x-7z-compressed"},
+}
+
+// detectBinary reports whether data looks like a binary file, along with a
+// best-guess MIME type. Data is binary if it starts with a known magic number,
+// contains a NUL byte, or is mostly invalid UTF-8 or control characters within
+// the first sniffLen bytes.
+func detectBinary(data []byte) (bool, string) {
+	if len(data) == 0 {
+		return false, ""
+	}
+	sample := data
+	if len(sample) > sniffLen {
+		sample = sample[:sniffLen]
+	}
+
+	for _, m := range magic {
+		if bytes.HasPrefix(sample, m.prefix) {
+			return true, m.mime
+		}
+	}
+	mime := http.DetectContentType(sample)
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = mime[:i]
+	}
+	if !strings.HasPrefix(mime, "text/") && mime != "application/octet-stream" &&
+		mime != "application/json" && mime != "application/xml" {
+		return true, mime
+	}
+	if bytes.IndexByte(sample, 0) >= 0 || suspiciousRatio(sample, len(data) > sniffLen) > 0.3 {
+		return true, "application/octet-stream"
+	}
+	return false, ""
+}
+
+// suspiciousRatio returns the fraction of bytes in sample that are invalid
+// UTF-8 or non-whitespace control characters. If truncated is set, a rune cut
+// off at the end of sample is not counted as invalid.
+func suspiciousRatio(sample []byte, truncated bool) float64 {
+	bad := 0
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRune(sample[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			if truncated && !utf8.FullRune(sample[i:]) {
+				i = len(sample)
+				continue
+			}
+			bad++
+		case r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f' && r != '\v' && r != 0x1b:
+			bad++
+		}
+		i += size
+	}
+	return float64(bad) / float64(len(sample))
+}
+
+// placeholder describes an omitted binary file, e.g.
+// "[binary file, 12.3 KiB, image/png omitted]".
+func placeholder(size int, mime string) string {
+	return fmt.Sprintf("[binary file, %s, %s omitted]\n", humanSize(size), mime)
+}
+
+// humanSize formats a byte count using binary units.
+func humanSize(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDetectBinary verifies binary classification using magic numbers, NUL bytes and the invalid UTF-8 ratio.
+func TestDetectBinary(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantBinary bool
+		wantMIME   string
+	}{
+		{name: "empty", data: []byte{}, wantBinary: false},
+		{name: "plain text", data: []byte("hello\nworld\n"), wantBinary: false},
+		{name: "utf-8 text", data: []byte("héllo wörld — ünïcödé ✓\n"), wantBinary: false},
+		{name: "json", data: []byte(`{"a": 1}`), wantBinary: false},
+		{name: "png", data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), wantBinary: true, wantMIME: "image/png"},
+		{name: "pdf", data: []byte("%PDF-1.7\n"), wantBinary: true, wantMIME: "application/pdf"},
+		{name: "gzip", data: []byte("\x1f\x8b\x08\x00\x00\x00"), wantBinary: true, wantMIME: "application/x-gzip"},
+		{name: "elf", data: []byte("\x7fELF\x02\x01\x01"), wantBinary: true, wantMIME: "application/x-elf"},
+		{name: "sqlite", data: []byte("SQLite format 3\x00rest"), wantBinary: true, wantMIME: "application/vnd.sqlite3"},
+		{name: "nul byte", data: []byte("text\x00more text"), wantBinary: true, wantMIME: "application/octet-stream"},
+		{name: "mostly invalid utf-8", data: []byte("a\xff\xfe\xfd\xfc\xfb\xfa\xf9"), wantBinary: true, wantMIME: "application/octet-stream"},
+		{name: "occasional latin-1 byte", data: []byte("caf\xe9 au lait, cr\xe8me br\xfbl\xe9e and other desserts\n"), wantBinary: false},
+		{
+			name:       "multibyte rune cut at sniff boundary",
+			data:       []byte(strings.Repeat("a", sniffLen-1) + "é"),
+			wantBinary: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binary, mime := detectBinary(tt.data)
+			assert.Equal(t, tt.wantBinary, binary)
+			if tt.wantBinary {
+				assert.Equal(t, tt.wantMIME, mime)
+			}
+		})
+	}
+}
+
+// TestParseBinaryMode verifies that --binary values map to modes and invalid values are rejected.
+func TestParseBinaryMode(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected BinaryMode
+		wantErr  bool
+	}{
+		{value: "skip", expected: BinarySkip},
+		{value: "placeholder", expected: BinaryPlaceholder},
+		{value: "INCLUDE", expected: BinaryInclude},
+		{value: "bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			mode, err := ParseBinaryMode(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, mode)
+		})
+	}
+}
+
+// TestHumanSize verifies byte counts are formatted with binary units.
+func TestHumanSize(t *testing.T) {
+	assert.Equal(t, "0 B", humanSize(0))
+	assert.Equal(t, "1023 B", humanSize(1023))
+	assert.Equal(t, "1.0 KiB", humanSize(1024))
+	assert.Equal(t, "12.3 KiB", humanSize(12595))
+	assert.Equal(t, "5.0 MiB", humanSize(5*1024*1024))
+}
+
+// TestMarkersBinaryModes verifies binary files are skipped with a warning or replaced by a placeholder section.
+func TestMarkersBinaryModes(t *testing.T) {
+	tmpDir := t.TempDir()
+	png := filepath.Join(tmpDir, "logo.png")
+	require.NoError(t, os.WriteFile(png, append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 2040)...), 0644))
+
+	t.Run("skip", func(t *testing.T) {
+		var out, warnings bytes.Buffer
+		Markers(&out, []string{png}, []string{"logo.png"},
+			Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>", Binary: BinarySkip, Warnings: &warnings})
+
+		assert.Empty(t, out.String())
+		assert.Contains(t, warnings.String(), "Skipping binary file "+png+" (image/png)")
+	})
+
+	t.Run("placeholder", func(t *testing.T) {
+		var out bytes.Buffer
+		Markers(&out, []string{png}, []string{"logo.png"}, Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>", Binary: BinaryPlaceholder})
+
+		assert.Equal(t, "<<<START:logo.png>>>\n[binary file, 2.0 KiB, image/png omitted]\n<<<END:logo.png>>>\n\n", out.String())
+	})
+}
@@ -0,0 +1,59 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// BudgetMode selects what happens when the next file would take the output
+// over Options.MaxTokens.
+type BudgetMode int
+
+const (
+	// BudgetStop leaves out that file and every file after it.
+	BudgetStop BudgetMode = iota
+	// BudgetTruncate emits as much of that file as fits, marked as
+	// truncated, and leaves out every file after it.
+	BudgetTruncate
+	// BudgetError fails without writing anything.
+	BudgetError
+)
+
+// ParseBudgetMode converts an --on-budget flag value to a BudgetMode.
+func ParseBudgetMode(s string) (BudgetMode, error) {
+	switch strings.ToLower(s) {
+	case "stop":
+		return BudgetStop, nil
+	case "truncate":
+		return BudgetTruncate, nil
+	case "error":
+		return BudgetError, nil
+	}
+	return BudgetStop, fmt.Errorf("invalid budget mode %q (want stop, truncate or error)", s)
+}
+
+// truncatedNote ends the content of a file cut short by BudgetTruncate.
+const truncatedNote = "[... truncated to fit the token budget ...]\n"
+
+// truncated returns the first n bytes of data, backed off to the end of the
+// last complete line if there is one, followed by truncatedNote. The result
+// grows with n, so the longest one that fits can be binary searched.
+func truncated(data []byte, n int) []byte {
+	cut := data[:n]
+	for i := 1; i < utf8.UTFMax && len(cut) > 0; i++ {
+		if r, _ := utf8.DecodeLastRune(cut); r != utf8.RuneError {
+			break
+		}
+		cut = cut[:len(cut)-1]
+	}
+	if i := bytes.LastIndexByte(cut, '\n'); i >= 0 {
+		cut = cut[:i+1]
+	}
+	out := append([]byte{}, cut...)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	return append(out, truncatedNote...)
+}
@@ -0,0 +1,133 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matthewchivers/txt2llm/pkg/tokens"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wordCounter counts whitespace-separated words, which keeps budget arithmetic in tests easy to follow.
+type wordCounter struct{}
+
+func (wordCounter) Count(text []byte) int { return len(bytes.Fields(text)) }
+
+var _ tokens.Counter = wordCounter{}
+
+// TestRenderBudget verifies that each budget mode stops, truncates or fails once the next file would not fit.
+func TestRenderBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	var files, outPaths []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(strings.Repeat(name+"\n", 20)), 0644))
+		files = append(files, path)
+		outPaths = append(outPaths, name)
+	}
+	// Each section is 24 words: 20 lines of content plus the heading and fences.
+	base := Options{Format: FormatMarkdown, Tokenizer: wordCounter{}, MaxTokens: 60}
+
+	t.Run("unlimited", func(t *testing.T) {
+		opts := base
+		opts.MaxTokens = 0
+		var buf bytes.Buffer
+		stats, err := Render(context.Background(), &buf, files, outPaths, opts)
+		require.NoError(t, err)
+		assert.Equal(t, 3, stats.Emitted())
+		assert.Equal(t, 72, stats.Tokens)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		var buf bytes.Buffer
+		stats, err := Render(context.Background(), &buf, files, outPaths, base)
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.Emitted())
+		assert.Equal(t, 48, stats.Tokens)
+		assert.Equal(t, "over limit", stats.Files[2].Skipped)
+		assert.NotContains(t, buf.String(), "c.txt")
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		opts := base
+		opts.MaxTokens = 65
+		opts.OnBudget = BudgetTruncate
+		var buf bytes.Buffer
+		stats, err := Render(context.Background(), &buf, files, outPaths, opts)
+		require.NoError(t, err)
+		assert.Equal(t, 3, stats.Emitted())
+		assert.True(t, stats.Files[2].Truncated)
+		// 17 words remain: the heading, fences and note take 12, leaving room for 5 lines.
+		assert.Equal(t, 65, stats.Tokens)
+		assert.Contains(t, buf.String(), "```text\n"+strings.Repeat("c.txt\n", 5)+truncatedNote+"```\n")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		opts := base
+		opts.OnBudget = BudgetError
+		var buf bytes.Buffer
+		_, err := Render(context.Background(), &buf, files, outPaths, opts)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "c.txt")
+		assert.Empty(t, buf.String())
+	})
+}
+
+// TestTruncated verifies content is cut at the last complete line and never inside a multi-byte rune.
+func TestTruncated(t *testing.T) {
+	assert.Equal(t, "one\n"+truncatedNote, string(truncated([]byte("one\ntwo\n"), 6)))
+	assert.Equal(t, truncatedNote, string(truncated([]byte("one\ntwo\n"), 0)))
+	assert.Equal(t, "ab\n"+truncatedNote, string(truncated([]byte("abé"), 3)))
+}
+
+// TestParseBudgetMode verifies that --on-budget values map to modes and invalid values are rejected.
+func TestParseBudgetMode(t *testing.T) {
+	for value, expected := range map[string]BudgetMode{"stop": BudgetStop, "truncate": BudgetTruncate, "ERROR": BudgetError} {
+		mode, err := ParseBudgetMode(value)
+		require.NoError(t, err)
+		assert.Equal(t, expected, mode)
+	}
+	_, err := ParseBudgetMode("drop")
+	assert.Error(t, err)
+}
+
+// TestRenderBudgetTree verifies that with a token budget the file tree lists only the files that are emitted and
+// marks the one that is truncated, and that the budget is only reported once.
+func TestRenderBudgetTree(t *testing.T) {
+	tmpDir := t.TempDir()
+	var files, outPaths []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(strings.Repeat(name+"\n", 20)), 0644))
+		files = append(files, path)
+		outPaths = append(outPaths, name)
+	}
+
+	for _, mode := range []BudgetMode{BudgetStop, BudgetTruncate} {
+		var buf, warnings bytes.Buffer
+		opts := Options{Format: FormatMarkdown, Tokenizer: wordCounter{}, MaxTokens: 60, OnBudget: mode, Tree: true, Warnings: &warnings}
+		stats, err := Render(context.Background(), &buf, files, outPaths, opts)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, stats.Tokens, 60)
+		assert.Less(t, stats.Emitted(), 3)
+		assert.Equal(t, 1, strings.Count(warnings.String(), "Token budget of 60 reached"), warnings.String())
+
+		require.True(t, strings.HasPrefix(buf.String(), "## File tree\n\n```text\n"))
+		tree, _, _ := strings.Cut(strings.TrimPrefix(buf.String(), "## File tree\n\n```text\n"), "```")
+		for _, st := range stats.Files {
+			switch {
+			case st.Skipped != "":
+				assert.NotContains(t, buf.String(), st.Path, "mode %d", mode)
+			case st.Truncated:
+				assert.Contains(t, tree, st.Path+" (truncated)", "mode %d", mode)
+			default:
+				assert.Contains(t, tree, st.Path+"\n", "mode %d", mode)
+			}
+		}
+	}
+}
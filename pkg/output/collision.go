@@ -0,0 +1,113 @@
+package output
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// CollisionMode selects what happens when a file contains text that looks
+// like one of the configured START/END markers.
+type CollisionMode int
+
+const (
+	// CollisionNonce appends a random nonce to the marker suffix for the whole
+	// run, so no file can contain a real marker line.
+	CollisionNonce CollisionMode = iota
+	// CollisionError fails before anything is emitted.
+	CollisionError
+)
+
+// ParseCollisionMode converts an --on-collision flag value to a CollisionMode.
+func ParseCollisionMode(s string) (CollisionMode, error) {
+	switch strings.ToLower(s) {
+	case "nonce":
+		return CollisionNonce, nil
+	case "error":
+		return CollisionError, nil
+	}
+	return CollisionNonce, fmt.Errorf("invalid collision mode %q (want nonce or error)", s)
+}
+
+// avoidCollisions returns options whose markers cannot appear in any of the
+// files p scanned: unchanged if nothing collides, otherwise with a
+// nonce-suffixed MarkerSuffix, or an error in CollisionError mode.
+func (p prescanned) avoidCollisions(opts Options) (Options, error) {
+	if p.colliding == "" {
+		return opts, nil
+	}
+	if opts.OnCollision == CollisionError {
+		return opts, fmt.Errorf("%s contains text matching the %sSTART:/%sEND: markers; "+
+			"choose different --marker-prefix/--marker-suffix or use --on-collision nonce",
+			p.colliding, opts.MarkerPrefix, opts.MarkerPrefix)
+	}
+
+	for {
+		nonce := newNonce()
+		if !anyContains(p.held, nonce) && !anyStreamContains(p.streams, nonce) {
+			opts.MarkerSuffix = "@" + nonce + opts.MarkerSuffix
+			return opts, nil
+		}
+	}
+}
+
+// markerIn reports whether the file at src contains the start of a marker,
+// unless it is binary and left out. data is its content, unless it streams.
+func markerIn(src string, data []byte, streams bool, opts Options) bool {
+	if streams {
+		return streamCollides(src, opts)
+	}
+	if binary, _ := detectBinary(data); binary && opts.Binary != BinaryInclude {
+		return false
+	}
+	return containsMarker(data, opts.MarkerPrefix)
+}
+
+// containsMarker reports whether data contains the start of a START or END
+// marker built from prefix.
+func containsMarker(data []byte, prefix string) bool {
+	return bytes.Contains(data, []byte(prefix+"START:")) || bytes.Contains(data, []byte(prefix+"END:"))
+}
+
+// anyContains reports whether any of contents contains s.
+func anyContains(contents [][]byte, s string) bool {
+	for _, data := range contents {
+		if bytes.Contains(data, []byte(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamCollides reports whether a file that streams contains the start of
+// a marker, unless it is binary and left out.
+func streamCollides(src string, opts Options) bool {
+	head, err := sniff(src)
+	if err != nil {
+		return false // reported when the file is emitted
+	}
+	if binary, _ := detectBinary(head); binary && opts.Binary != BinaryInclude {
+		return false
+	}
+	found, _ := streamContains(src, opts.MarkerPrefix+"START:", opts.MarkerPrefix+"END:")
+	return found
+}
+
+// anyStreamContains reports whether any of the files at paths contains s.
+func anyStreamContains(paths []string, s string) bool {
+	for _, p := range paths {
+		if found, _ := streamContains(p, s); found {
+			return true
+		}
+	}
+	return false
+}
+
+// newNonce returns a short random hex string.
+func newNonce() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,100 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAvoidCollisions verifies markers are left alone without collisions, made unique with a nonce on collision,
+// or rejected in error mode.
+func TestAvoidCollisions(t *testing.T) {
+	tmpDir := t.TempDir()
+	clean := filepath.Join(tmpDir, "clean.go")
+	dirty := filepath.Join(tmpDir, "dirty_test.go")
+	require.NoError(t, os.WriteFile(clean, []byte("package clean\n"), 0644))
+	require.NoError(t, os.WriteFile(dirty, []byte("want := \"<<<END:foo.go>>>\"\n"), 0644))
+
+	base := Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>"}
+	avoidCollisions := func(files []string, opts Options) (Options, error) {
+		scanned, err := prescan(context.Background(), files, files, opts, false)
+		require.NoError(t, err)
+		return scanned.avoidCollisions(opts)
+	}
+
+	t.Run("no collision", func(t *testing.T) {
+		opts, err := avoidCollisions([]string{clean}, base)
+		require.NoError(t, err)
+		assert.Equal(t, base, opts)
+	})
+
+	t.Run("nonce on collision", func(t *testing.T) {
+		opts, err := avoidCollisions([]string{clean, dirty}, base)
+		require.NoError(t, err)
+		assert.Equal(t, "<<<", opts.MarkerPrefix)
+		assert.Regexp(t, `^@[0-9a-f]{8}>>>$`, opts.MarkerSuffix)
+	})
+
+	t.Run("error on collision", func(t *testing.T) {
+		opts := base
+		opts.OnCollision = CollisionError
+		_, err := avoidCollisions([]string{clean, dirty}, opts)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), dirty)
+	})
+
+	t.Run("different markers do not collide", func(t *testing.T) {
+		opts := Options{MarkerPrefix: "[[[", MarkerSuffix: "]]]"}
+		got, err := avoidCollisions([]string{dirty}, opts)
+		require.NoError(t, err)
+		assert.Equal(t, opts, got)
+	})
+
+	t.Run("skipped binary files are ignored", func(t *testing.T) {
+		bin := filepath.Join(tmpDir, "blob.bin")
+		require.NoError(t, os.WriteFile(bin, []byte("\x00<<<END:x>>>"), 0644))
+		got, err := avoidCollisions([]string{bin}, base)
+		require.NoError(t, err)
+		assert.Equal(t, base, got)
+	})
+}
+
+// TestRenderCollisionNonce verifies that the nonce-suffixed markers are used for the header and every section.
+func TestRenderCollisionNonce(t *testing.T) {
+	tmpDir := t.TempDir()
+	dirty := filepath.Join(tmpDir, "dirty.txt")
+	require.NoError(t, os.WriteFile(dirty, []byte("<<<END:dirty.txt>>>\n"), 0644))
+
+	var buf bytes.Buffer
+	_, err := Render(context.Background(), &buf, []string{dirty}, []string{"dirty.txt"}, Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>"})
+	require.NoError(t, err)
+	output := buf.String()
+
+	nonce := regexp.MustCompile(`@([0-9a-f]{8})>>>`).FindStringSubmatch(output)
+	require.NotNil(t, nonce)
+	suffix := "@" + nonce[1] + ">>>"
+	assert.Contains(t, output, "Delimiters: <<<START:{filename}"+suffix+" ... <<<END:{filename}"+suffix+"\n")
+	assert.Contains(t, output, "<<<START:dirty.txt"+suffix+"\n<<<END:dirty.txt>>>\n<<<END:dirty.txt"+suffix+"\n")
+	assert.Equal(t, 1, strings.Count(output, "<<<END:dirty.txt"+suffix))
+}
+
+// TestParseCollisionMode verifies that --on-collision values map to modes and invalid values are rejected.
+func TestParseCollisionMode(t *testing.T) {
+	mode, err := ParseCollisionMode("nonce")
+	require.NoError(t, err)
+	assert.Equal(t, CollisionNonce, mode)
+
+	mode, err = ParseCollisionMode("error")
+	require.NoError(t, err)
+	assert.Equal(t, CollisionError, mode)
+
+	_, err = ParseCollisionMode("rename")
+	assert.Error(t, err)
+}
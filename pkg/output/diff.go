@@ -0,0 +1,78 @@
+package output
+
+import (
+	"path/filepath"
+
+	"github.com/matthewchivers/txt2llm/pkg/diff"
+)
+
+// diffSuffix is appended to a file's path to name the section holding its
+// diff.
+const diffSuffix = ".diff"
+
+// section is one delimited block of output.
+type section struct {
+	path string
+	data []byte
+	// src, if set, names a file too large to hold that is copied into the
+	// section in chunks when it is written, instead of data; redact says
+	// whether to mask secrets in it.
+	src    string
+	redact bool
+}
+
+// sections loads srcPath and returns the sections to emit for it: the file
+// itself, or in diff mode its diff against opts.DiffBase followed by the
+// whole file if it is smaller than opts.DiffFullUnder. If the file should be
+// left out, the returned FileStats says why. Its Bytes and Lines count the
+// content of every section. A file that streams is returned as a section
+// to copy when written.
+func sections(srcPath, outPath string, opts Options) ([]section, FileStats) {
+	if size, ok := opts.streams(srcPath); ok {
+		return streamSection(srcPath, outPath, size, opts)
+	}
+	data, st := load(srcPath, outPath, opts)
+	if st.Skipped != "" || opts.DiffBase == nil {
+		return []section{{path: outPath, data: data}}, st
+	}
+
+	old, exists, err := opts.DiffBase(srcPath)
+	if err != nil {
+		st.Skipped = "unreadable"
+		warn(opts, "Error reading the base of %s: %v\n", srcPath, err)
+		return nil, st
+	}
+	if binary, mime := detectBinary(old); binary {
+		switch opts.Binary {
+		case BinarySkip:
+			st.Skipped = "binary"
+			warn(opts, "Skipping %s: its base is a binary file (%s)\n", srcPath, mime)
+			return nil, st
+		case BinaryPlaceholder:
+			old = []byte(placeholder(len(old), mime))
+		}
+	} else if opts.Secrets != SecretOff {
+		// Secrets removed since the base would otherwise show in the diff.
+		old, _ = opts.redactor().Redact(old)
+	}
+	name := filepath.ToSlash(outPath)
+	oldName := "a/" + name
+	if !exists {
+		oldName = "/dev/null"
+	}
+	d := diff.UnifiedContext(oldName, "b/"+name, old, data, opts.DiffContext)
+	if d == "" {
+		st.Skipped = "unchanged"
+		return nil, st
+	}
+	secs := []section{{path: outPath + diffSuffix, data: []byte(d)}}
+	if len(data) < opts.DiffFullUnder {
+		secs = append(secs, section{path: outPath, data: data})
+	}
+	st.Bytes, st.Lines = 0, 0
+	for _, s := range secs {
+		st.Bytes += len(s.data)
+		st.Lines += countLines(s.data)
+	}
+	return secs, st
+}
@@ -0,0 +1,121 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderDiff verifies that diff mode emits a .diff section per changed file, skips unchanged files, diffs new
+// files against /dev/null and adds the whole of small files.
+func TestRenderDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"changed.txt": "one\ntwo\nthree\nfour\nfive\n",
+		"same.txt":    "same\n",
+		"new.txt":     "new\n",
+	}
+	bases := map[string]string{
+		"changed.txt": "one\n2\nthree\nfour\nfive\n",
+		"same.txt":    "same\n",
+	}
+	var srcs, outPaths []string
+	for _, name := range []string{"changed.txt", "same.txt", "new.txt"} {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(files[name]), 0644))
+		srcs = append(srcs, path)
+		outPaths = append(outPaths, name)
+	}
+	opts := Options{
+		MarkerPrefix: "<<<",
+		MarkerSuffix: ">>>",
+		DiffContext:  1,
+		DiffBase: func(path string) ([]byte, bool, error) {
+			base, ok := bases[filepath.Base(path)]
+			return []byte(base), ok, nil
+		},
+	}
+
+	var buf bytes.Buffer
+	stats, err := Render(context.Background(), &buf, srcs, outPaths, opts)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "<<<START:changed.txt.diff>>>\n--- a/changed.txt\n+++ b/changed.txt\n@@ -1,3 +1,3 @@\n one\n-2\n+two\n three\n<<<END:changed.txt.diff>>>\n")
+	assert.Contains(t, buf.String(), "<<<START:new.txt.diff>>>\n--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+new\n<<<END:new.txt.diff>>>\n")
+	assert.NotContains(t, buf.String(), "same.txt")
+	assert.NotContains(t, buf.String(), "<<<START:new.txt>>>")
+	assert.Equal(t, "unchanged", stats.Files[1].Skipped)
+	assert.Equal(t, 2, stats.Emitted())
+
+	opts.DiffFullUnder = 10
+	buf.Reset()
+	stats, err = Render(context.Background(), &buf, srcs, outPaths, opts)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "<<<END:new.txt.diff>>>\n\n<<<START:new.txt>>>\nnew\n<<<END:new.txt>>>\n")
+	assert.NotContains(t, buf.String(), "<<<START:changed.txt>>>", "files at or over the threshold only get a diff")
+	assert.Equal(t, len("--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+new\n")+len("new\n"), stats.Files[2].Bytes)
+
+	buf.Reset()
+	XML(&buf, srcs, outPaths, opts)
+	assert.Contains(t, buf.String(), "<document index=\"2\">\n<source>new.txt.diff</source>")
+	assert.Contains(t, buf.String(), "<document index=\"3\">\n<source>new.txt</source>")
+}
+
+// TestRenderDiffBinaryBase verifies that the binary policy applies to a file's diff base as well as the file, and that
+// secrets are only reported for files whose diff is emitted.
+func TestRenderDiffBinaryBase(t *testing.T) {
+	tmpDir := t.TempDir()
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	files := map[string]string{
+		"logo.png":   png + "new",
+		"config.txt": "password=hunter22\n",
+	}
+	var srcs, outPaths []string
+	for _, name := range []string{"logo.png", "config.txt"} {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(files[name]), 0644))
+		srcs = append(srcs, path)
+		outPaths = append(outPaths, name)
+	}
+	bases := map[string]string{
+		"logo.png":   png + "old",
+		"config.txt": "password=hunter22\n",
+	}
+	render := func(binary BinaryMode) (string, string, Stats) {
+		var buf, warnings bytes.Buffer
+		opts := Options{
+			MarkerPrefix: "<<<",
+			MarkerSuffix: ">>>",
+			Binary:       binary,
+			Warnings:     &warnings,
+			DiffBase: func(path string) ([]byte, bool, error) {
+				return []byte(bases[filepath.Base(path)]), true, nil
+			},
+		}
+		stats, err := Render(context.Background(), &buf, srcs, outPaths, opts)
+		require.NoError(t, err)
+		return buf.String(), warnings.String(), stats
+	}
+
+	out, warnings, stats := render(BinaryPlaceholder)
+	assert.NotContains(t, out, "PNG")
+	assert.Equal(t, "unchanged", stats.Files[0].Skipped, "a placeholder only changes with the size")
+	assert.Equal(t, "unchanged", stats.Files[1].Skipped)
+	assert.NotContains(t, warnings, "Redacted")
+
+	bases["logo.png"] = png + "older"
+	out, _, _ = render(BinaryPlaceholder)
+	assert.Contains(t, out, "-[binary file, 21 B, image/png omitted]\n+[binary file, 19 B, image/png omitted]\n")
+	assert.NotContains(t, out, "PNG")
+
+	files["logo.png"] = "now text\n"
+	require.NoError(t, os.WriteFile(srcs[0], []byte(files["logo.png"]), 0644))
+	out, warnings, stats = render(BinarySkip)
+	assert.NotContains(t, out, "logo.png")
+	assert.Equal(t, "binary", stats.Files[0].Skipped)
+	assert.Contains(t, warnings, "Skipping "+srcs[0]+": its base is a binary file (image/png)\n")
+}
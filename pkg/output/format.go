@@ -0,0 +1,39 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Format selects the overall structure of the output.
+type Format int
+
+const (
+	// FormatMarkers delimits files with START/END marker lines.
+	FormatMarkers Format = iota
+	// FormatXML wraps files in <documents>/<document> tags.
+	FormatXML
+	// FormatMarkdown gives each file a heading and a fenced code block.
+	FormatMarkdown
+	// FormatJSON emits a single object with a files array.
+	FormatJSON
+	// FormatJSONL emits one JSON object per file per line.
+	FormatJSONL
+)
+
+// ParseFormat converts a --format flag value to a Format.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "markers":
+		return FormatMarkers, nil
+	case "xml":
+		return FormatXML, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	case "json":
+		return FormatJSON, nil
+	case "jsonl":
+		return FormatJSONL, nil
+	}
+	return FormatMarkers, fmt.Errorf("invalid format %q (want markers, xml, markdown, json or jsonl)", s)
+}
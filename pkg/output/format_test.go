@@ -0,0 +1,37 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseFormat verifies that --format values map to formats and invalid values are rejected.
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Format
+		wantErr  bool
+	}{
+		{value: "markers", expected: FormatMarkers},
+		{value: "XML", expected: FormatXML},
+		{value: "markdown", expected: FormatMarkdown},
+		{value: "md", expected: FormatMarkdown},
+		{value: "json", expected: FormatJSON},
+		{value: "jsonl", expected: FormatJSONL},
+		{value: "yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			format, err := ParseFormat(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// jsonFile is the JSON representation of one emitted file. Size and SHA256
+// describe the emitted content. Content that is not valid UTF-8 is base64
+// encoded, with Encoding set to "base64", so it survives the round trip.
+type jsonFile struct {
+	Path     string `json:"path"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding,omitempty"`
+	Size     int    `json:"size"`
+	SHA256   string `json:"sha256"`
+	Revision string `json:"revision,omitempty"`
+}
+
+func newJSONFile(outPath string, data []byte) jsonFile {
+	sum := sha256.Sum256(data)
+	f := jsonFile{
+		Path:   outPath,
+		Size:   len(data),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+	if utf8.Valid(data) {
+		f.Content = string(data)
+	} else {
+		f.Content = base64.StdEncoding.EncodeToString(data)
+		f.Encoding = "base64"
+	}
+	return f
+}
+
+// JSON writes all files to w as a single {"files": [...]} object, one file
+// per line.
+func JSON(w io.Writer, files []string, outPaths []string, opts Options) {
+	_, _ = render(context.Background(), w, files, outPaths, opts, &jsonFormatter{})
+}
+
+// jsonFormatter writes a {"files": [...]} object, with "revision" and "tree"
+// strings first if they are set. Each file carries the revision too.
+type jsonFormatter struct {
+	tree     string
+	revision string
+	sep      string
+}
+
+func (f *jsonFormatter) begin(w io.Writer) {
+	fmt.Fprint(w, "{")
+	if f.revision != "" {
+		fmt.Fprintf(w, "\"revision\":%s,\n", marshalJSON(f.revision))
+	}
+	if f.tree != "" {
+		fmt.Fprintf(w, "\"tree\":%s,\n", marshalJSON(f.tree))
+	}
+	fmt.Fprint(w, `"files":[`)
+	f.sep = "\n"
+}
+
+func (f *jsonFormatter) file(w io.Writer, _ int, outPath string, data []byte) {
+	fmt.Fprint(w, f.sep)
+	jf := newJSONFile(outPath, data)
+	jf.Revision = f.revision
+	_, _ = w.Write(marshalJSON(jf))
+	f.sep = ",\n"
+}
+
+func (f *jsonFormatter) end(w io.Writer) {
+	fmt.Fprint(w, "\n]}\n")
+}
+
+// JSONL writes one JSON object per file to w, one per line, as each file is
+// read.
+func JSONL(w io.Writer, files []string, outPaths []string, opts Options) {
+	_, _ = render(context.Background(), w, files, outPaths, opts, jsonlFormatter{})
+}
+
+// jsonlFormatter writes one JSON object per line, each carrying the revision
+// if one is set.
+type jsonlFormatter struct {
+	revision string
+}
+
+func (jsonlFormatter) begin(io.Writer) {}
+
+func (f jsonlFormatter) file(w io.Writer, _ int, outPath string, data []byte) {
+	jf := newJSONFile(outPath, data)
+	jf.Revision = f.revision
+	_, _ = w.Write(marshalJSON(jf))
+	fmt.Fprintln(w)
+}
+
+func (jsonlFormatter) end(io.Writer) {}
+
+// marshalJSON encodes v on a single line without escaping HTML characters,
+// which keeps code readable.
+func marshalJSON(v any) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
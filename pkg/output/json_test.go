@@ -0,0 +1,91 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJSON verifies that JSON output is a single valid object whose files carry path, content, size and sha256.
+func TestJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	file1 := filepath.Join(tmpDir, "a.go")
+	file2 := filepath.Join(tmpDir, "b.html")
+	require.NoError(t, os.WriteFile(file1, []byte("package a\n"), 0644))
+	require.NoError(t, os.WriteFile(file2, []byte("<p>\"quoted\"</p>"), 0644))
+
+	var buf bytes.Buffer
+	JSON(&buf, []string{file1, file2}, []string{"a.go", "b.html"}, Options{})
+
+	var parsed struct {
+		Files []jsonFile `json:"files"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &parsed))
+	require.Len(t, parsed.Files, 2)
+	assert.Equal(t, newJSONFile("a.go", []byte("package a\n")), parsed.Files[0])
+	assert.Equal(t, 10, parsed.Files[0].Size)
+	assert.Equal(t, "<p>\"quoted\"</p>", parsed.Files[1].Content)
+	assert.Contains(t, buf.String(), "<p>", "HTML characters should not be escaped")
+}
+
+// TestJSONEmpty verifies that JSON output stays valid when every file is skipped.
+func TestJSONEmpty(t *testing.T) {
+	tmpDir := t.TempDir()
+	bin := filepath.Join(tmpDir, "a.bin")
+	require.NoError(t, os.WriteFile(bin, []byte("\x00\x01"), 0644))
+
+	var buf bytes.Buffer
+	JSON(&buf, []string{bin}, []string{"a.bin"}, Options{})
+
+	var parsed struct {
+		Files []jsonFile `json:"files"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &parsed))
+	assert.Empty(t, parsed.Files)
+}
+
+// TestJSONL verifies that JSONL output has one valid object per line and base64-encodes invalid UTF-8.
+func TestJSONL(t *testing.T) {
+	tmpDir := t.TempDir()
+	file1 := filepath.Join(tmpDir, "a.txt")
+	file2 := filepath.Join(tmpDir, "latin1.txt")
+	require.NoError(t, os.WriteFile(file1, []byte("line one\nline two"), 0644))
+	require.NoError(t, os.WriteFile(file2, []byte("caf\xe9 cr\xe8me"), 0644))
+
+	var buf bytes.Buffer
+	JSONL(&buf, []string{file1, file2}, []string{"a.txt", "latin1.txt"}, Options{})
+
+	var lines []jsonFile
+	sc := bufio.NewScanner(strings.NewReader(buf.String()))
+	for sc.Scan() {
+		var f jsonFile
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &f))
+		lines = append(lines, f)
+	}
+	require.Len(t, lines, 2)
+
+	assert.Equal(t, "a.txt", lines[0].Path)
+	assert.Equal(t, "line one\nline two", lines[0].Content)
+	assert.Empty(t, lines[0].Encoding)
+
+	assert.Equal(t, "base64", lines[1].Encoding)
+	decoded, err := base64.StdEncoding.DecodeString(lines[1].Content)
+	require.NoError(t, err)
+	assert.Equal(t, "caf\xe9 cr\xe8me", string(decoded))
+	assert.Equal(t, len(decoded), lines[1].Size)
+}
+
+// TestNewJSONFile verifies the sha256 digest matches the emitted content.
+func TestNewJSONFile(t *testing.T) {
+	f := newJSONFile("x.txt", []byte("hello"))
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", f.SHA256)
+	assert.Equal(t, 5, f.Size)
+}
@@ -0,0 +1,169 @@
+package output
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// languageByName maps well-known file names without a telling extension to
+// code fence languages.
+var languageByName = map[string]string{
+	"makefile":       "makefile",
+	"gnumakefile":    "makefile",
+	"dockerfile":     "dockerfile",
+	"containerfile":  "dockerfile",
+	"cmakelists.txt": "cmake",
+	"jenkinsfile":    "groovy",
+	"gemfile":        "ruby",
+	"rakefile":       "ruby",
+	"vagrantfile":    "ruby",
+	"go.mod":         "go",
+	"go.sum":         "text",
+	".bashrc":        "bash",
+	".bash_profile":  "bash",
+	".zshrc":         "zsh",
+	".gitignore":     "gitignore",
+	".txt2llmignore": "gitignore",
+	".dockerignore":  "gitignore",
+	".editorconfig":  "ini",
+}
+
+// languageByExt maps lower-case file extensions to code fence languages.
+var languageByExt = map[string]string{
+	".go":         "go",
+	".py":         "python",
+	".pyi":        "python",
+	".rb":         "ruby",
+	".rs":         "rust",
+	".js":         "javascript",
+	".mjs":        "javascript",
+	".cjs":        "javascript",
+	".jsx":        "jsx",
+	".ts":         "typescript",
+	".tsx":        "tsx",
+	".java":       "java",
+	".kt":         "kotlin",
+	".kts":        "kotlin",
+	".scala":      "scala",
+	".swift":      "swift",
+	".c":          "c",
+	".h":          "c",
+	".cc":         "cpp",
+	".cpp":        "cpp",
+	".cxx":        "cpp",
+	".hpp":        "cpp",
+	".cs":         "csharp",
+	".fs":         "fsharp",
+	".php":        "php",
+	".pl":         "perl",
+	".lua":        "lua",
+	".r":          "r",
+	".dart":       "dart",
+	".ex":         "elixir",
+	".exs":        "elixir",
+	".erl":        "erlang",
+	".hs":         "haskell",
+	".clj":        "clojure",
+	".sh":         "bash",
+	".bash":       "bash",
+	".zsh":        "zsh",
+	".fish":       "fish",
+	".ps1":        "powershell",
+	".bat":        "batch",
+	".sql":        "sql",
+	".html":       "html",
+	".htm":        "html",
+	".css":        "css",
+	".scss":       "scss",
+	".less":       "less",
+	".vue":        "vue",
+	".svelte":     "svelte",
+	".xml":        "xml",
+	".svg":        "xml",
+	".json":       "json",
+	".jsonl":      "json",
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".toml":       "toml",
+	".ini":        "ini",
+	".cfg":        "ini",
+	".proto":      "protobuf",
+	".graphql":    "graphql",
+	".tf":         "hcl",
+	".hcl":        "hcl",
+	".md":         "markdown",
+	".markdown":   "markdown",
+	".rst":        "rst",
+	".tex":        "latex",
+	".diff":       "diff",
+	".patch":      "diff",
+	".mk":         "makefile",
+	".cmake":      "cmake",
+	".dockerfile": "dockerfile",
+	".txt":        "text",
+}
+
+// languageByInterpreter maps shebang interpreters to code fence languages.
+var languageByInterpreter = map[string]string{
+	"sh":      "sh",
+	"bash":    "bash",
+	"zsh":     "zsh",
+	"fish":    "fish",
+	"python":  "python",
+	"python3": "python",
+	"node":    "javascript",
+	"deno":    "typescript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+	"Rscript": "r",
+}
+
+// language infers a code fence language from a file's name, extension or
+// shebang line, returning "" if none applies.
+func language(path string, data []byte) string {
+	base := filepath.Base(path)
+	if lang, ok := languageByName[strings.ToLower(base)]; ok {
+		return lang
+	}
+	if lang, ok := languageByExt[strings.ToLower(filepath.Ext(base))]; ok {
+		return lang
+	}
+	if strings.HasPrefix(strings.ToLower(base), "dockerfile.") {
+		return "dockerfile"
+	}
+	return languageFromShebang(data)
+}
+
+// languageFromShebang maps a "#!" first line to a language, looking through
+// "/usr/bin/env" to the real interpreter.
+func languageFromShebang(data []byte) string {
+	if !bytes.HasPrefix(data, []byte("#!")) {
+		return ""
+	}
+	line := data[2:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interp = f
+				break
+			}
+		}
+	}
+	if lang, ok := languageByInterpreter[interp]; ok {
+		return lang
+	}
+	// Versioned interpreters such as python3.12 or ruby2.7.
+	return languageByInterpreter[strings.TrimRight(interp, "0123456789.")]
+}
@@ -0,0 +1,55 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Markdown writes each file to w as a heading followed by a fenced code block
+// tagged with the file's inferred language.
+func Markdown(w io.Writer, files []string, outPaths []string, opts Options) {
+	_, _ = render(context.Background(), w, files, outPaths, opts, markdownFormatter{})
+}
+
+// markdownFormatter writes a heading and fenced code block per file, after an
+// optional revision note and file tree.
+type markdownFormatter struct {
+	tree     string
+	revision string
+}
+
+func (f markdownFormatter) begin(w io.Writer) {
+	if f.revision != "" {
+		fmt.Fprintf(w, revisionNote, "`"+f.revision+"`")
+	}
+	if f.tree != "" {
+		fmt.Fprintf(w, "## File tree\n\n```text\n%s```\n\n", f.tree)
+	}
+}
+
+func (markdownFormatter) file(w io.Writer, _ int, outPath string, data []byte) {
+	fence := fenceFor(data)
+	fmt.Fprintf(w, "## %s\n\n%s%s\n", outPath, fence, language(outPath, data))
+	_, _ = w.Write(data)
+	newlineIfNeeded(w, data)
+	fmt.Fprintf(w, "%s\n\n", fence)
+}
+
+func (markdownFormatter) end(io.Writer) {}
+
+// fenceFor returns a backtick fence longer than any backtick run in data, so
+// the content can never close the code block early.
+func fenceFor(data []byte) string {
+	longest, run := 0, 0
+	for _, b := range data {
+		if b == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
@@ -0,0 +1,76 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMarkdown verifies that each file gets a heading and a language-tagged fence that its content cannot close.
+func TestMarkdown(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	goFile := filepath.Join(tmpDir, "main.go")
+	mdFile := filepath.Join(tmpDir, "README.md")
+	require.NoError(t, os.WriteFile(goFile, []byte("package main"), 0644))
+	require.NoError(t, os.WriteFile(mdFile, []byte("# Title\n\n```sh\nmake\n```\n"), 0644))
+
+	var buf bytes.Buffer
+	Markdown(&buf, []string{goFile, mdFile}, []string{"main.go", "README.md"}, Options{})
+
+	expected := "## main.go\n\n```go\npackage main\n```\n\n" +
+		"## README.md\n\n````markdown\n# Title\n\n```sh\nmake\n```\n````\n\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+// TestFenceFor verifies the fence is always longer than the longest backtick run in the content.
+func TestFenceFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{name: "no backticks", data: "plain", expected: "```"},
+		{name: "inline code", data: "use `x` here", expected: "```"},
+		{name: "triple fence", data: "```go\n```", expected: "````"},
+		{name: "long run mid-line", data: "a `````` b", expected: "```````"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, fenceFor([]byte(tt.data)))
+		})
+	}
+}
+
+// TestLanguage verifies language inference from extensions, well-known file names and shebang lines.
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		path     string
+		data     string
+		expected string
+	}{
+		{path: "main.go", expected: "go"},
+		{path: "src/App.TSX", expected: "tsx"},
+		{path: "Makefile", expected: "makefile"},
+		{path: "build/Dockerfile", expected: "dockerfile"},
+		{path: "Dockerfile.dev", expected: "dockerfile"},
+		{path: "CMakeLists.txt", expected: "cmake"},
+		{path: "scripts/deploy", data: "#!/bin/bash\necho hi\n", expected: "bash"},
+		{path: "tool", data: "#!/usr/bin/env python3\n", expected: "python"},
+		{path: "tool", data: "#!/usr/bin/env -S node --harmony\n", expected: "javascript"},
+		{path: "tool", data: "#!/usr/local/bin/python3.12\n", expected: "python"},
+		{path: "tool", data: "#!/opt/unknown\n", expected: ""},
+		{path: "LICENSE", data: "MIT", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, language(tt.path, []byte(tt.data)))
+		})
+	}
+}
@@ -2,15 +2,87 @@
 package output
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/matthewchivers/txt2llm/pkg/redact"
+	"github.com/matthewchivers/txt2llm/pkg/tokens"
 )
 
-// Header prints a concise explanation of markers.
-func Header(markerPrefix, markerSuffix string) {
-	fmt.Printf("Each section below represents text output from one file.\n")
-	fmt.Printf("Delimiters: %sSTART:{filename}%s ... %sEND:{filename}%s\n\n", markerPrefix, markerSuffix, markerPrefix, markerSuffix)
+// Options controls how files are emitted.
+type Options struct {
+	// Format selects the overall output structure.
+	Format       Format
+	MarkerPrefix string
+	MarkerSuffix string
+	// Binary selects how files detected as binary are handled.
+	Binary BinaryMode
+	// OnCollision selects what happens when a file contains the markers.
+	OnCollision CollisionMode
+	// Warnings receives a note for each file left out of the output. Nil
+	// discards them.
+	Warnings io.Writer
+	// Tokenizer counts tokens for Stats and the budget; nil uses
+	// tokens.Heuristic.
+	Tokenizer tokens.Counter
+	// MaxTokens caps the tokens in the whole output; 0 means no limit.
+	MaxTokens int
+	// OnBudget selects what happens once MaxTokens would be exceeded.
+	OnBudget BudgetMode
+	// Tree adds a tree of the emitted files before the first file. Files
+	// are read once beforehand so skipped ones can be left out.
+	Tree bool
+	// TreeSizes annotates each tree node with its size and tokens.
+	TreeSizes bool
+	// ReadFile reads the content of a file; nil uses os.ReadFile.
+	ReadFile func(path string) ([]byte, error)
+	// Revision, if set, names the git revision the contents come from. It
+	// is noted before the files and with each of them, e.g. in the START
+	// marker as "a.go@v1.2 (3f2a9c1)".
+	Revision string
+	// DiffBase, if set, replaces each file with a "path.diff" section
+	// holding its unified diff against the content DiffBase returns, which
+	// does not exist for a new file. Unchanged files are skipped.
+	DiffBase func(path string) (data []byte, exists bool, err error)
+	// DiffContext is the number of unchanged lines around each change.
+	DiffContext int
+	// DiffFullUnder also emits the whole of each changed file smaller than
+	// this many bytes, after its diff.
+	DiffFullUnder int
+	// Secrets selects what happens to secrets and sensitive files.
+	Secrets SecretMode
+	// Redactor finds secrets and sensitive files; nil uses redact.New().
+	Redactor *redact.Redactor
+	// Jobs is the number of files read and prepared at once; 0 or 1 reads
+	// them one at a time. Output and warnings keep the order of the files.
+	Jobs int
+	// MaxBuffered caps the bytes of content read ahead of the file being
+	// written; 0 means no limit.
+	MaxBuffered int64
+	// StreamOver copies files larger than this many bytes to the output in
+	// chunks instead of reading them whole, where the format allows; 0
+	// reads every file whole.
+	StreamOver int64
+}
+
+// revisionNote introduces files read from a git revision.
+const revisionNote = "All files are shown as of git revision %s.\n\n"
+
+// readFile reads path with opts.ReadFile, or from disk if it is nil.
+func (opts Options) readFile(path string) ([]byte, error) {
+	if opts.ReadFile != nil {
+		return opts.ReadFile(path)
+	}
+	return os.ReadFile(path)
+}
+
+// Header writes a concise explanation of markers.
+func Header(w io.Writer, markerPrefix, markerSuffix string) {
+	fmt.Fprintf(w, "Each section below represents text output from one file.\n")
+	fmt.Fprintf(w, "Delimiters: %sSTART:{filename}%s ... %sEND:{filename}%s\n\n", markerPrefix, markerSuffix, markerPrefix, markerSuffix)
 }
 
 // Paths returns either absolute or relative paths depending on flag.
@@ -30,28 +102,47 @@ func Paths(files []string, relative bool) []string {
 	return rel
 }
 
-// Markers emits all files with start/end markers.
-func Markers(files []string, outPaths []string, markerPrefix, markerSuffix string) {
-	for i, src := range files {
-		emit(src, outPaths[i], markerPrefix, markerSuffix)
-	}
+// Markers writes all files to w with start/end markers.
+func Markers(w io.Writer, files []string, outPaths []string, opts Options) {
+	_, _ = render(context.Background(), w, files, outPaths, opts, markersFormatter{opts: opts})
 }
 
-func emit(srcPath, outPath, markerPrefix, markerSuffix string) {
-	data, err := os.ReadFile(srcPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", srcPath, err)
-		return
+// markersFormatter delimits each file with START/END marker lines, optionally
+// after a Header explaining them and a file tree.
+type markersFormatter struct {
+	opts   Options
+	header bool
+	tree   string
+}
+
+func (f markersFormatter) begin(w io.Writer) {
+	if f.header {
+		Header(w, f.opts.MarkerPrefix, f.opts.MarkerSuffix)
+	}
+	if f.opts.Revision != "" {
+		fmt.Fprintf(w, revisionNote, f.opts.Revision)
 	}
-	fmt.Printf("%sSTART:%s%s\n", markerPrefix, outPath, markerSuffix)
-	_, _ = os.Stdout.Write(data) // Ignore write errors to stdout
-	newlineIfNeeded(data)
-	fmt.Printf("%sEND:%s%s\n\n", markerPrefix, outPath, markerSuffix)
+	if f.tree != "" {
+		fmt.Fprintf(w, "%s\n", f.tree)
+	}
+}
+
+func (f markersFormatter) file(w io.Writer, index int, outPath string, data []byte) {
+	f.open(w, index, outPath)
+	_, _ = w.Write(data)
+	newlineIfNeeded(w, data)
+	f.close(w, index, outPath)
 }
 
-func newlineIfNeeded(data []byte) {
-	if len(data) == 0 || data[len(data)-1] == '\n' {
-		return
+func (f markersFormatter) open(w io.Writer, _ int, outPath string) {
+	if f.opts.Revision != "" {
+		outPath += "@" + f.opts.Revision
 	}
-	fmt.Println()
+	fmt.Fprintf(w, "%sSTART:%s%s\n", f.opts.MarkerPrefix, outPath, f.opts.MarkerSuffix)
+}
+
+func (f markersFormatter) close(w io.Writer, _ int, outPath string) {
+	fmt.Fprintf(w, "%sEND:%s%s\n\n", f.opts.MarkerPrefix, outPath, f.opts.MarkerSuffix)
 }
+
+func (markersFormatter) end(io.Writer) {}
@@ -40,19 +40,9 @@ func TestHeader(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Capture stdout
-			old := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			Header(tt.markerPrefix, tt.markerSuffix)
-
-			w.Close()
-			os.Stdout = old
-
-			buf := make([]byte, 1024)
-			n, _ := r.Read(buf)
-			output := string(buf[:n])
+			var buf bytes.Buffer
+			Header(&buf, tt.markerPrefix, tt.markerSuffix)
+			output := buf.String()
 
 			assert.Equal(t, tt.expected, output)
 		})
@@ -184,19 +174,9 @@ func TestMarkers(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Capture stdout
-			old := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			Markers(tt.files, tt.outPaths, tt.markerPrefix, tt.markerSuffix)
-
-			w.Close()
-			os.Stdout = old
-
-			buf := make([]byte, 4096)
-			n, _ := r.Read(buf)
-			output := string(buf[:n])
+			var buf bytes.Buffer
+			Markers(&buf, tt.files, tt.outPaths, Options{MarkerPrefix: tt.markerPrefix, MarkerSuffix: tt.markerSuffix})
+			output := buf.String()
 
 			// Check that all expected content is present
 			for _, expected := range tt.expectedContains {
@@ -206,8 +186,9 @@ func TestMarkers(t *testing.T) {
 	}
 }
 
-// TestEmit verifies the emit function handles various file content types (with/without newlines, empty, binary) correctly.
-func TestEmit(t *testing.T) {
+// TestMarkersContent verifies markers output handles various file content types (with/without newlines, empty, binary)
+// correctly.
+func TestMarkersContent(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	tests := []struct {
@@ -216,6 +197,7 @@ func TestEmit(t *testing.T) {
 		outPath          string
 		markerPrefix     string
 		markerSuffix     string
+		binary           BinaryMode
 		expectedContains []string
 	}{
 		{
@@ -259,6 +241,7 @@ func TestEmit(t *testing.T) {
 			outPath:      "binary.dat",
 			markerPrefix: "---",
 			markerSuffix: "---",
+			binary:       BinaryInclude,
 			expectedContains: []string{
 				"---START:binary.dat---",
 				"binary\x00\x01\x02content",
@@ -273,19 +256,9 @@ func TestEmit(t *testing.T) {
 			testFile := filepath.Join(tmpDir, tt.name+".txt")
 			require.NoError(t, os.WriteFile(testFile, []byte(tt.content), 0644))
 
-			// Capture stdout
-			old := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			emit(testFile, tt.outPath, tt.markerPrefix, tt.markerSuffix)
-
-			w.Close()
-			os.Stdout = old
-
-			buf := make([]byte, 4096)
-			n, _ := r.Read(buf)
-			output := string(buf[:n])
+			var buf bytes.Buffer
+			Markers(&buf, []string{testFile}, []string{tt.outPath}, Options{MarkerPrefix: tt.markerPrefix, MarkerSuffix: tt.markerSuffix, Binary: tt.binary})
+			output := buf.String()
 
 			// Check expected content
 			for _, expected := range tt.expectedContains {
@@ -295,23 +268,14 @@ func TestEmit(t *testing.T) {
 	}
 }
 
-// TestEmitWithNonexistentFile ensures emit function reports errors gracefully when trying to read missing files.
-func TestEmitWithNonexistentFile(t *testing.T) {
-	// Capture stderr
-	old := os.Stderr
-	r, w, _ := os.Pipe()
-	os.Stderr = w
-
-	emit("nonexistent.txt", "nonexistent.txt", "<<<", ">>>")
+// TestMarkersWithNonexistentFile ensures missing files are reported as warnings and left out of the output.
+func TestMarkersWithNonexistentFile(t *testing.T) {
+	var out, warnings bytes.Buffer
+	Markers(&out, []string{"nonexistent.txt"}, []string{"nonexistent.txt"},
+		Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>", Warnings: &warnings})
 
-	w.Close()
-	os.Stderr = old
-
-	buf := make([]byte, 1024)
-	n, _ := r.Read(buf)
-	output := string(buf[:n])
-
-	assert.Contains(t, output, "Error reading nonexistent.txt")
+	assert.Empty(t, out.String())
+	assert.Contains(t, warnings.String(), "Error reading nonexistent.txt")
 }
 
 // TestNewlineIfNeeded verifies that newlineIfNeeded only adds newlines when file content doesn't end with one.
@@ -345,18 +309,8 @@ func TestNewlineIfNeeded(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Capture stdout
-			old := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			newlineIfNeeded(tt.data)
-
-			w.Close()
-			os.Stdout = old
-
 			var buf bytes.Buffer
-			buf.ReadFrom(r)
+			newlineIfNeeded(&buf, tt.data)
 			output := buf.String()
 
 			assert.Equal(t, tt.expected, output)
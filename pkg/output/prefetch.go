@@ -0,0 +1,175 @@
+package output
+
+import (
+	"bytes"
+	"sync"
+)
+
+// loaded is a file read and prepared by a prefetcher's prepare function.
+type loaded struct {
+	secs     []section
+	st       FileStats
+	scan     fileScan // what prescan found, when it prepared the file
+	warnings []byte   // notes to pass on to opts.Warnings when it is used
+	size     int      // bytes held in secs and scan
+}
+
+// prepareFunc prepares the file at src, shown as outPath, for the caller of
+// a prefetcher.
+type prepareFunc func(src, outPath string, opts Options) loaded
+
+// prepareSections prepares a file for emission with sections.
+func prepareSections(src, outPath string, opts Options) loaded {
+	secs, st := sections(src, outPath, opts)
+	return loaded{secs: secs, st: st}
+}
+
+// prefetcher reads files ahead of the emit loop with up to opts.Jobs
+// workers, handing them over in their original order. Workers do not start
+// a file while opts.MaxBuffered bytes are waiting to be used, unless it is
+// the next file needed, so huge files are not all held at once. If secrets
+// are pseudonymised, workers only read ahead and prepare files in order, so
+// each value gets the same number however the reads interleave.
+type prefetcher struct {
+	files    []string
+	outPaths []string
+	opts     Options
+	prepare  prepareFunc
+	results  []chan loaded
+	ordered  bool // prepare files in order, after reading them
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	claimed  int   // files handed to workers so far
+	next     int   // the file get will be asked for next
+	buffered int64 // bytes loaded but not yet taken by get
+	stopped  bool
+	wg       sync.WaitGroup
+}
+
+// prefetch starts reading files and preparing them with prepare. The caller
+// must call get or take for files in order, and stop once it needs no more
+// of them.
+func prefetch(files, outPaths []string, opts Options, prepare prepareFunc) *prefetcher {
+	p := &prefetcher{files: files, outPaths: outPaths, opts: opts, prepare: prepare}
+	p.ordered = opts.Secrets != SecretOff && opts.redactor().Pseudonymises()
+	p.cond = sync.NewCond(&p.mu)
+	if opts.Jobs <= 1 {
+		return p
+	}
+	p.results = make([]chan loaded, len(files))
+	for i := range p.results {
+		p.results[i] = make(chan loaded, 1)
+	}
+	for range min(opts.Jobs, len(files)) {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// work loads files until there are none left or the prefetcher is stopped.
+func (p *prefetcher) work() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		if p.stopped || p.claimed == len(p.files) {
+			p.mu.Unlock()
+			return
+		}
+		i := p.claimed
+		p.claimed++
+		for !p.stopped && i != p.next && p.opts.MaxBuffered > 0 && p.buffered >= p.opts.MaxBuffered {
+			p.cond.Wait()
+		}
+		if p.stopped {
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		l := p.load(i)
+		p.mu.Lock()
+		p.buffered += int64(l.size)
+		p.mu.Unlock()
+		p.results[i] <- l
+	}
+}
+
+// load prepares file i, collecting its warnings rather than writing them so
+// they come out in order.
+func (p *prefetcher) load(i int) loaded {
+	opts := p.opts
+	var warnings bytes.Buffer
+	if opts.Warnings != nil {
+		opts.Warnings = &warnings
+	}
+	warnWhole(p.files[i], opts)
+	if p.results != nil && p.ordered {
+		opts = p.read(i, opts)
+		p.mu.Lock()
+		for !p.stopped && i != p.next {
+			p.cond.Wait()
+		}
+		p.mu.Unlock()
+	}
+	l := p.prepare(p.files[i], p.outPaths[i], opts)
+	l.warnings = warnings.Bytes()
+	for _, s := range l.secs {
+		l.size += len(s.data)
+	}
+	l.size += len(l.scan.held)
+	return l
+}
+
+// read reads file i and its diff base now, returning opts that hand back
+// what was read.
+func (p *prefetcher) read(i int, opts Options) Options {
+	src := p.files[i]
+	if _, ok := opts.streams(src); ok {
+		return opts // read as it is written
+	}
+	data, err := opts.readFile(src)
+	opts.ReadFile = func(string) ([]byte, error) { return data, err }
+	if base := opts.DiffBase; base != nil {
+		old, exists, err := base(src)
+		opts.DiffBase = func(string) ([]byte, bool, error) { return old, exists, err }
+	}
+	return opts
+}
+
+// get returns the sections for file i, which must be the file after the one
+// last asked for, and writes its warnings.
+func (p *prefetcher) get(i int) ([]section, FileStats) {
+	l := p.take(i)
+	return l.secs, l.st
+}
+
+// take is like get, returning all that was prepared for file i.
+func (p *prefetcher) take(i int) loaded {
+	var l loaded
+	if p.results == nil {
+		l = p.load(i)
+	} else {
+		l = <-p.results[i]
+		p.mu.Lock()
+		p.buffered -= int64(l.size)
+		p.next = i + 1
+		p.cond.Broadcast()
+		p.mu.Unlock()
+	}
+	if p.opts.Warnings != nil {
+		_, _ = p.opts.Warnings.Write(l.warnings)
+	}
+	return l
+}
+
+// stop tells the workers to start no more files and waits for them to
+// finish the ones they are reading.
+func (p *prefetcher) stop() {
+	p.mu.Lock()
+	p.stopped = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+	p.wg.Wait()
+}
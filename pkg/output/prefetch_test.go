@@ -0,0 +1,133 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matthewchivers/txt2llm/pkg/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderJobs verifies that reading files concurrently, with or without a memory cap, produces the same output,
+// warnings and stats as reading them one at a time, including pseudonyms and budget cut-offs.
+func TestRenderJobs(t *testing.T) {
+	contents := map[string][]byte{}
+	var files []string
+	for i := range 40 {
+		name := fmt.Sprintf("file%02d.txt", i)
+		switch i % 5 {
+		case 0:
+			contents[name] = []byte("binary\x00data")
+		case 1:
+			contents[name] = []byte(fmt.Sprintf("customer=%d and customer=%d\n", 40-i, i%3))
+		case 2:
+			contents[name] = []byte(strings.Repeat("a large file\n", 200))
+		default:
+			contents[name] = []byte(fmt.Sprintf("file %d\n", i))
+		}
+		files = append(files, name)
+	}
+	files = append(files, "missing.txt")
+
+	rules, err := redact.ParseRules("rules.yaml", []byte("rules:\n  - name: customer\n    pattern: 'customer=(\\d+)'\n    pseudonymise: true\n"))
+	require.NoError(t, err)
+	readFile := func(path string) ([]byte, error) {
+		time.Sleep(time.Duration(rand.IntN(200)) * time.Microsecond)
+		data, ok := contents[path]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return data, nil
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "markers", opts: Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>"}},
+		{name: "pseudonyms", opts: Options{Format: FormatJSONL}},
+		{name: "budget", opts: Options{Format: FormatXML, MaxTokens: 1500, OnBudget: BudgetTruncate}},
+		{name: "tree", opts: Options{Format: FormatMarkdown, TreeSizes: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			render := func(jobs int, maxBuffered int64) (string, string, Stats) {
+				opts := tt.opts
+				opts.ReadFile = readFile
+				opts.Jobs = jobs
+				opts.MaxBuffered = maxBuffered
+				opts.Redactor = redact.New(rules...)
+				var buf, warnings bytes.Buffer
+				opts.Warnings = &warnings
+				stats, err := Render(context.Background(), &buf, files, files, opts)
+				require.NoError(t, err)
+				return buf.String(), warnings.String(), stats
+			}
+
+			out, warnings, stats := render(1, 0)
+			for _, run := range []struct {
+				jobs        int
+				maxBuffered int64
+			}{{8, 0}, {8, 1}, {3, 4096}} {
+				gotOut, gotWarnings, gotStats := render(run.jobs, run.maxBuffered)
+				assert.Equal(t, out, gotOut, "jobs %d, max buffered %d", run.jobs, run.maxBuffered)
+				assert.Equal(t, warnings, gotWarnings, "jobs %d, max buffered %d", run.jobs, run.maxBuffered)
+				assert.Equal(t, stats, gotStats, "jobs %d, max buffered %d", run.jobs, run.maxBuffered)
+			}
+		})
+	}
+}
+
+// TestRenderJobsCancelled verifies that a cancelled render stops its workers and returns the context's error.
+func TestRenderJobsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	files := make([]string, 100)
+	for i := range files {
+		files[i] = fmt.Sprintf("file%d", i)
+	}
+	opts := Options{Jobs: 4, ReadFile: func(string) ([]byte, error) {
+		cancel()
+		return []byte("x\n"), nil
+	}}
+
+	_, err := Render(ctx, &bytes.Buffer{}, files, files, opts)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestRenderPrescanReadsOnce verifies that the checks made before writing and the file tree share one read of each
+// file, made with the same workers as rendering.
+func TestRenderPrescanReadsOnce(t *testing.T) {
+	files := []string{"a.txt", "b.txt", "c.txt", "d.txt"}
+	var mu sync.Mutex
+	reads := map[string]int{}
+	inFlight, most := 0, 0
+	readFile := func(path string) ([]byte, error) {
+		mu.Lock()
+		reads[path]++
+		inFlight++
+		most = max(most, inFlight)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return []byte("<<<END:" + path + ">>>\n"), nil
+	}
+
+	opts := Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>", Tree: true, Secrets: SecretError, Jobs: 4, ReadFile: readFile}
+	_, err := Render(context.Background(), &bytes.Buffer{}, files, files, opts)
+	require.NoError(t, err)
+	for _, f := range files {
+		assert.Equal(t, 2, reads[f], "%s should be read once before writing and once to emit it", f)
+	}
+	assert.Greater(t, most, 1, "files should be scanned concurrently")
+}
@@ -0,0 +1,92 @@
+package output
+
+import "context"
+
+// prescanned is what Render learns about the files before it writes
+// anything.
+type prescanned struct {
+	files     []FileStats // the stats of every file, if measured
+	colliding string      // the first file containing a marker, if any
+	held      [][]byte    // the content of files containing a marker
+	streams   []string    // files containing a marker too large to hold
+}
+
+// fileScan is what prescan finds in one file.
+type fileScan struct {
+	err    error  // why the run must stop, in SecretError mode
+	marker bool   // whether the file contains the start of a marker
+	held   []byte // its content if it contains one and does not stream
+}
+
+// prescan reads each file once, with the same workers and memory cap as
+// rendering, for what Render must know before it writes anything: the first
+// sensitive file or secret in SecretError mode, which is returned as an
+// error, the files containing a marker in the marker format, and, if
+// measured is set, the stats of every file for the file tree. Warnings are
+// left for when the files are emitted.
+func prescan(ctx context.Context, files, outPaths []string, opts Options, measured bool) (prescanned, error) {
+	opts.Warnings = nil
+	pf := prefetch(files, outPaths, opts, func(src, outPath string, opts Options) loaded {
+		return scanFile(src, outPath, opts, measured)
+	})
+	defer pf.stop()
+
+	var p prescanned
+	for i, src := range files {
+		if err := ctx.Err(); err != nil {
+			return p, err
+		}
+		l := pf.take(i)
+		if l.scan.err != nil {
+			return p, l.scan.err
+		}
+		if l.scan.marker {
+			if p.colliding == "" {
+				p.colliding = src
+			}
+			if l.scan.held != nil {
+				p.held = append(p.held, l.scan.held)
+			} else {
+				p.streams = append(p.streams, src)
+			}
+		}
+		if measured {
+			p.files = append(p.files, l.st)
+		}
+	}
+	return p, nil
+}
+
+// scanFile reads the file at src once for prescan. A file that streams is
+// read in chunks for each check instead.
+func scanFile(src, outPath string, opts Options, measured bool) loaded {
+	var l loaded
+	if opts.Secrets == SecretError && opts.redactor().Sensitive(src) {
+		l.scan.err = sensitiveError(src)
+		return l
+	}
+	_, streams := opts.streams(src)
+	var data []byte
+	var err error
+	if !streams {
+		data, err = opts.readFile(src)
+		opts.ReadFile = func(string) ([]byte, error) { return data, err }
+	}
+	if err == nil { // otherwise reported when the file is emitted
+		if opts.Secrets == SecretError {
+			if l.scan.err = secretIn(src, data, streams, opts); l.scan.err != nil {
+				return l
+			}
+		}
+		if opts.Format == FormatMarkers && markerIn(src, data, streams, opts) {
+			l.scan.marker = true
+			if !streams {
+				l.scan.held = data
+			}
+		}
+	}
+	if measured {
+		l.st = measure(src, outPath, opts)
+	}
+	return l
+}
@@ -0,0 +1,420 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/matthewchivers/txt2llm/pkg/redact"
+	"github.com/matthewchivers/txt2llm/pkg/tokens"
+)
+
+// overLimit is why a file left out for the token budget is skipped.
+const overLimit = "over limit"
+
+// formatter writes one output format. Write errors are collected by the
+// stickyWriter that Render wraps around the destination.
+type formatter interface {
+	// begin writes anything that precedes the first file.
+	begin(w io.Writer)
+	// file writes the section for one file; index counts emitted files from 1.
+	file(w io.Writer, index int, outPath string, data []byte)
+	// end writes anything that follows the last file.
+	end(w io.Writer)
+}
+
+// streamFormatter is a formatter that can write a file's section around
+// content copied in separately, for files that stream.
+type streamFormatter interface {
+	formatter
+	// open writes what precedes the content of a file's section.
+	open(w io.Writer, index int, outPath string)
+	// close writes what follows it, after a final line break.
+	close(w io.Writer, index int, outPath string)
+}
+
+// Render writes all files to w in the format selected by opts, including any
+// preamble the format needs. It fails before writing anything if the marker
+// format is selected and the markers collide with file contents in
+// CollisionError mode, if a file holds a secret in SecretError mode, or if
+// the token budget is exceeded in BudgetError mode. The checks that must be
+// made before writing, and the file tree, read the files with the same
+// workers as rendering, so each file is read at most once beforehand. It
+// stops early if ctx is cancelled, and otherwise returns the first
+// error writing to w.
+func Render(ctx context.Context, w io.Writer, files []string, outPaths []string, opts Options) (Stats, error) {
+	measure := opts.Tree || opts.TreeSizes
+	if measure && opts.Format == FormatJSONL {
+		return Stats{}, fmt.Errorf("a file tree cannot be added to jsonl output")
+	}
+	var scanned prescanned
+	if measure || opts.Secrets == SecretError || opts.Format == FormatMarkers {
+		var err error
+		if scanned, err = prescan(ctx, files, outPaths, opts, measure); err != nil {
+			return Stats{}, err
+		}
+	}
+	opts, err := scanned.avoidCollisions(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	sw := &stickyWriter{w: w}
+	var stats Stats
+	switch {
+	case measure && opts.MaxTokens > 0:
+		stats, err = renderFitted(ctx, sw, files, outPaths, opts, scanned.files)
+	case measure:
+		stats, err = render(ctx, sw, files, outPaths, opts, newFormatter(opts, buildTree(scanned.files, opts.TreeSizes)))
+	default:
+		stats, err = render(ctx, sw, files, outPaths, opts, newFormatter(opts, ""))
+	}
+	stats.Written = sw.n
+	if err == nil {
+		err = sw.err
+	}
+	return stats, err
+}
+
+// newFormatter returns the formatter for opts.Format, adding tree if it is
+// set and the format allows one.
+func newFormatter(opts Options, tree string) formatter {
+	switch opts.Format {
+	case FormatXML:
+		return xmlFormatter{tree: tree, revision: opts.Revision}
+	case FormatMarkdown:
+		return markdownFormatter{tree: tree, revision: opts.Revision}
+	case FormatJSON:
+		return &jsonFormatter{tree: tree, revision: opts.Revision}
+	case FormatJSONL:
+		return jsonlFormatter{revision: opts.Revision}
+	default:
+		return markersFormatter{opts: opts, header: true, tree: tree}
+	}
+}
+
+// renderFitted renders with a file tree listing only the files that fit
+// opts.MaxTokens. What fits depends on the size of the tree, so the output is
+// rendered into memory, which the budget keeps small, until the tree matches
+// what was emitted. Files left out for a larger tree stay out, so this ends.
+func renderFitted(ctx context.Context, w io.Writer, files, outPaths []string, opts Options, measured []FileStats) (Stats, error) {
+	listed := measured
+	stop := len(files)
+	for {
+		tree := buildTree(listed, opts.TreeSizes)
+		var buf, warnings bytes.Buffer
+		pass := opts
+		if opts.Warnings != nil {
+			pass.Warnings = &warnings
+		}
+		stats, err := renderUpTo(ctx, &buf, files, outPaths, pass, newFormatter(pass, tree), stop)
+		if err == nil {
+			var cut int
+			listed, cut = fitted(measured, stats.Files)
+			stop = min(stop, cut)
+			if buildTree(listed, opts.TreeSizes) != tree {
+				continue
+			}
+			_, _ = buf.WriteTo(w)
+		}
+		if opts.Warnings != nil {
+			_, _ = warnings.WriteTo(opts.Warnings)
+		}
+		return stats, err
+	}
+}
+
+// fitted returns measured with the files a render left out for the token
+// budget marked as skipped and those it cut short marked as truncated, and
+// the index of the first file it left out for the budget.
+func fitted(measured, rendered []FileStats) ([]FileStats, int) {
+	listed := slices.Clone(measured)
+	cut := len(rendered)
+	for i, st := range rendered {
+		if st.Skipped == overLimit {
+			listed[i].Skipped = overLimit
+			cut = min(cut, i)
+		}
+		listed[i].Truncated = st.Truncated
+	}
+	return listed, cut
+}
+
+// render loads each file, reading ahead with opts.Jobs workers, and writes the
+// ones that should be emitted using f in their original order, keeping within
+// opts.MaxTokens.
+func render(ctx context.Context, w io.Writer, files []string, outPaths []string, opts Options, f formatter) (Stats, error) {
+	return renderUpTo(ctx, w, files, outPaths, opts, f, len(files))
+}
+
+// renderUpTo is like render, but treats the budget as reached at
+// files[stop].
+func renderUpTo(ctx context.Context, w io.Writer, files []string, outPaths []string, opts Options, f formatter, stop int) (Stats, error) {
+	r := &renderer{w: w, f: f, opts: opts, counter: opts.Tokenizer}
+	if r.counter == nil {
+		r.counter = tokens.Heuristic{}
+	}
+	var held bytes.Buffer
+	if opts.MaxTokens > 0 && opts.OnBudget == BudgetError {
+		r.w = &held
+	}
+
+	pf := prefetch(files, outPaths, opts, prepareSections)
+	defer pf.stop()
+
+	var stats Stats
+	r.flush(r.section(f.begin))
+	r.reserve = r.section(f.end)
+	index := 0
+	full := false
+	for i, src := range files {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if i == stop && !full {
+			full = true
+			pf.stop()
+			warn(opts, "Token budget of %d reached at %s; skipping it and any remaining files\n", opts.MaxTokens, src)
+		}
+		if full {
+			stats.Files = append(stats.Files, FileStats{Source: src, Path: outPaths[i], Skipped: overLimit})
+			continue
+		}
+		secs, st := pf.get(i)
+		if st.Skipped != "" {
+			stats.Files = append(stats.Files, st)
+			continue
+		}
+		if secs[0].src != "" {
+			index++
+			err := r.stream(index, secs[0], &st)
+			stats.Files = append(stats.Files, st)
+			if err != nil {
+				return stats, err
+			}
+			continue
+		}
+		n := r.section(func(w io.Writer) {
+			for j, s := range secs {
+				f.file(w, index+1+j, s.path, s.data)
+			}
+		})
+		if !r.fits(n) {
+			full = true
+			pf.stop()
+			switch opts.OnBudget {
+			case BudgetError:
+				return stats, fmt.Errorf("%s would take the output to %d tokens, over the budget of %d",
+					src, r.total+n+r.reserve, opts.MaxTokens)
+			case BudgetTruncate:
+				// Only the first section, the diff in diff mode, is kept.
+				secs = secs[:1]
+				secs[0].data, n = r.truncate(index+1, secs[0].path, secs[0].data)
+			default:
+				n = -1
+			}
+			if n < 0 {
+				warn(opts, "Token budget of %d reached at %s; skipping it and any remaining files\n", opts.MaxTokens, src)
+				stats.Files = append(stats.Files, FileStats{Source: src, Path: outPaths[i], Skipped: overLimit})
+				continue
+			}
+			warn(opts, "Token budget of %d reached at %s; truncating it and skipping any remaining files\n", opts.MaxTokens, src)
+			st.Bytes = len(secs[0].data)
+			st.Lines = countLines(secs[0].data)
+			st.Truncated = true
+		}
+		index += len(secs)
+		r.flush(n)
+		warnRedacted(st, opts)
+		st.Tokens = 0
+		for _, s := range secs {
+			st.Tokens += r.counter.Count(s.data)
+		}
+		stats.Files = append(stats.Files, st)
+	}
+	r.flush(r.section(f.end))
+	stats.Tokens = r.total
+
+	if r.w == &held {
+		_, _ = held.WriteTo(w)
+	}
+	return stats, nil
+}
+
+// renderer renders sections into a scratch buffer so their tokens can be
+// counted before they are written.
+type renderer struct {
+	w       io.Writer
+	f       formatter
+	opts    Options
+	counter tokens.Counter
+	buf     bytes.Buffer
+	total   int // tokens written so far
+	reserve int // tokens the formatter's end will need
+}
+
+// section renders write into the scratch buffer and returns its token count.
+func (r *renderer) section(write func(io.Writer)) int {
+	r.buf.Reset()
+	write(&r.buf)
+	return r.counter.Count(r.buf.Bytes())
+}
+
+// flush writes the scratch buffer, which holds n tokens.
+func (r *renderer) flush(n int) {
+	_, _ = r.w.Write(r.buf.Bytes())
+	r.total += n
+}
+
+// fits reports whether a section of n tokens fits in the budget, leaving
+// room for the formatter's end.
+func (r *renderer) fits(n int) bool {
+	return r.opts.MaxTokens <= 0 || r.total+n+r.reserve <= r.opts.MaxTokens
+}
+
+// stream writes the section of a file that streams, copying its content
+// straight to the output. If reading fails part way, the section is still
+// closed, so the output stays well-formed, before the error is returned.
+// Streaming is only chosen without a token budget, so it always fits.
+func (r *renderer) stream(index int, s section, st *FileStats) error {
+	f := r.f.(streamFormatter)
+	head := r.section(func(w io.Writer) { f.open(w, index, s.path) })
+	r.flush(head)
+	out, err := copyStream(r.w, s, r.opts, r.counter)
+	tail := r.section(func(w io.Writer) {
+		if out.bytes > 0 && out.last != '\n' {
+			fmt.Fprintln(w)
+		}
+		f.close(w, index, s.path)
+	})
+	r.flush(tail)
+	r.total += out.tokens
+
+	st.Bytes = out.bytes
+	st.Lines = out.lines
+	st.Tokens = out.tokens
+	noteRedactions(st, out.findings, r.opts)
+	st.Truncated = err != nil
+	return err
+}
+
+// truncate finds the longest truncated form of data whose section fits the
+// budget, leaving that section in the scratch buffer. It returns the content
+// and the section's token count, or n < 0 if nothing fits.
+func (r *renderer) truncate(index int, outPath string, data []byte) ([]byte, int) {
+	try := func(size int) int {
+		return r.section(func(w io.Writer) { r.f.file(w, index, outPath, truncated(data, size)) })
+	}
+	if !r.fits(try(0)) {
+		return nil, -1
+	}
+	lo, hi := 0, len(data)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if r.fits(try(mid)) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return truncated(data, lo), try(lo)
+}
+
+// load reads srcPath and applies the secret and binary policies, returning
+// the content to emit. If the file should be left out, the returned
+// FileStats says why and a note is written to opts.Warnings. Redacted
+// secrets are recorded in the FileStats but only noted once the file is
+// written, since it may yet be left out.
+func load(srcPath, outPath string, opts Options) ([]byte, FileStats) {
+	st := FileStats{Source: srcPath, Path: outPath}
+	if sensitive(srcPath, opts) {
+		st.Skipped = "sensitive"
+		return nil, st
+	}
+	data, err := opts.readFile(srcPath)
+	if err != nil {
+		st.Skipped = "unreadable"
+		warn(opts, "Error reading %s: %v\n", srcPath, err)
+		return nil, st
+	}
+	if binary, mime := detectBinary(data); binary {
+		switch opts.Binary {
+		case BinarySkip:
+			st.Skipped = "binary"
+			warn(opts, "Skipping binary file %s (%s)\n", srcPath, mime)
+			return nil, st
+		case BinaryPlaceholder:
+			data = []byte(placeholder(len(data), mime))
+		}
+	} else if opts.Secrets != SecretOff {
+		var findings []redact.Finding
+		data, findings = opts.redactor().Redact(data)
+		for _, f := range findings {
+			st.Redacted = append(st.Redacted, f.Type)
+		}
+	}
+	st.Bytes = len(data)
+	st.Lines = countLines(data)
+	return data, st
+}
+
+// sensitive reports whether srcPath is left out as a sensitive file, noting
+// it on opts.Warnings.
+func sensitive(srcPath string, opts Options) bool {
+	if opts.Secrets == SecretOff || !opts.redactor().Sensitive(srcPath) {
+		return false
+	}
+	warn(opts, "Skipping sensitive file %s\n", srcPath)
+	return true
+}
+
+// noteRedactions records the secrets found in a file in st and notes them on
+// opts.Warnings.
+func noteRedactions(st *FileStats, findings []redact.Finding, opts Options) {
+	for _, f := range findings {
+		st.Redacted = append(st.Redacted, f.Type)
+	}
+	warnRedacted(*st, opts)
+}
+
+// warnRedacted notes the secrets st records as redacted on opts.Warnings.
+func warnRedacted(st FileStats, opts Options) {
+	if len(st.Redacted) > 0 {
+		warn(opts, "Redacted %s in %s (%s)\n", secrets(len(st.Redacted)), st.Source, strings.Join(distinct(st.Redacted), ", "))
+	}
+}
+
+// warn writes a note to opts.Warnings, if set.
+func warn(opts Options, format string, args ...any) {
+	if opts.Warnings != nil {
+		fmt.Fprintf(opts.Warnings, format, args...)
+	}
+}
+
+func newlineIfNeeded(w io.Writer, data []byte) {
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return
+	}
+	fmt.Fprintln(w)
+}
+
+// stickyWriter counts bytes written and remembers the first write error, so
+// rendering code can write freely and report failure once at the end.
+type stickyWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (s *stickyWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	n, err := s.w.Write(p)
+	s.n += int64(n)
+	s.err = err
+	return n, err
+}
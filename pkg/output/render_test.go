@@ -0,0 +1,100 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matthewchivers/txt2llm/pkg/tokens"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderStats verifies that Render reports every input file, why skipped files were left out, and the number of
+// bytes written.
+func TestRenderStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	txt := filepath.Join(tmpDir, "a.txt")
+	bin := filepath.Join(tmpDir, "b.bin")
+	missing := filepath.Join(tmpDir, "missing.txt")
+	require.NoError(t, os.WriteFile(txt, []byte("hello\n"), 0644))
+	require.NoError(t, os.WriteFile(bin, []byte("\x00\x01"), 0644))
+
+	var buf, warnings bytes.Buffer
+	stats, err := Render(context.Background(), &buf, []string{txt, bin, missing}, []string{"a.txt", "b.bin", "missing.txt"},
+		Options{Format: FormatJSONL, Warnings: &warnings})
+	require.NoError(t, err)
+
+	require.Len(t, stats.Files, 3)
+	assert.Positive(t, stats.Files[0].Tokens)
+	stats.Files[0].Tokens = 0
+	assert.Equal(t, []FileStats{
+		{Source: txt, Path: "a.txt", Bytes: 6, Lines: 1},
+		{Source: bin, Path: "b.bin", Skipped: "binary"},
+		{Source: missing, Path: "missing.txt", Skipped: "unreadable"},
+	}, stats.Files)
+	assert.Equal(t, 1, stats.Emitted())
+	assert.Equal(t, int64(buf.Len()), stats.Written)
+	assert.Equal(t, tokens.Heuristic{}.Count(buf.Bytes()), stats.Tokens)
+	assert.Contains(t, warnings.String(), "Skipping binary file "+bin)
+	assert.Contains(t, warnings.String(), "Error reading "+missing)
+}
+
+// TestRenderCancelled verifies that Render stops before reading files once its context is cancelled.
+func TestRenderCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	txt := filepath.Join(tmpDir, "a.txt")
+	require.NoError(t, os.WriteFile(txt, []byte("hello\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	stats, err := Render(ctx, &buf, []string{txt}, []string{"a.txt"}, Options{Format: FormatJSONL})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, stats.Files)
+	assert.Empty(t, buf.String())
+}
+
+// TestRenderRevision verifies that contents come from ReadFile when it is set and that every format notes the
+// revision, with each file where the format allows.
+func TestRenderRevision(t *testing.T) {
+	contents := map[string]string{"/repo/a.go": "package a\n"}
+	opts := Options{
+		MarkerPrefix: "<<<",
+		MarkerSuffix: ">>>",
+		Revision:     "v1.0 (3f2a9c1)",
+		ReadFile: func(path string) ([]byte, error) {
+			if c, ok := contents[path]; ok {
+				return []byte(c), nil
+			}
+			return nil, os.ErrNotExist
+		},
+	}
+
+	tests := []struct {
+		name     string
+		format   Format
+		expected string
+	}{
+		{"markers", FormatMarkers,
+			"All files are shown as of git revision v1.0 (3f2a9c1).\n\n<<<START:a.go@v1.0 (3f2a9c1)>>>\npackage a\n<<<END:a.go>>>\n"},
+		{"markdown", FormatMarkdown, "All files are shown as of git revision `v1.0 (3f2a9c1)`.\n\n## a.go\n"},
+		{"xml", FormatXML, "<revision>v1.0 (3f2a9c1)</revision>\n<document index=\"1\">\n<source revision=\"v1.0 (3f2a9c1)\">a.go</source>\n"},
+		{"json", FormatJSON, `,"revision":"v1.0 (3f2a9c1)"}` + "\n]}\n"},
+		{"jsonl", FormatJSONL, `,"revision":"v1.0 (3f2a9c1)"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := opts
+			opts.Format = tt.format
+			var buf bytes.Buffer
+			stats, err := Render(context.Background(), &buf, []string{"/repo/a.go"}, []string{"a.go"}, opts)
+			require.NoError(t, err)
+			assert.Contains(t, buf.String(), tt.expected)
+			assert.Equal(t, 10, stats.Files[0].Bytes)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/matthewchivers/txt2llm/pkg/redact"
+)
+
+// SecretMode selects what happens to secrets and sensitive files.
+type SecretMode int
+
+const (
+	// SecretRedact masks secrets as [REDACTED:type] and skips sensitive
+	// files, noting each on stderr.
+	SecretRedact SecretMode = iota
+	// SecretError fails before writing anything if any file is sensitive or
+	// contains a secret.
+	SecretError
+	// SecretOff emits files as they are.
+	SecretOff
+)
+
+// ParseSecretMode converts an --on-secret flag value to a SecretMode.
+func ParseSecretMode(s string) (SecretMode, error) {
+	switch strings.ToLower(s) {
+	case "redact":
+		return SecretRedact, nil
+	case "error":
+		return SecretError, nil
+	case "off":
+		return SecretOff, nil
+	}
+	return SecretRedact, fmt.Errorf("invalid secret mode %q (want redact, error or off)", s)
+}
+
+// redactor returns opts.Redactor, or the default one if it is nil.
+func (opts Options) redactor() *redact.Redactor {
+	if opts.Redactor != nil {
+		return opts.Redactor
+	}
+	return redact.New()
+}
+
+// sensitiveError is the error for a sensitive file in SecretError mode.
+func sensitiveError(src string) error {
+	return fmt.Errorf("%s is a sensitive file; leave it out or use --on-secret redact to skip it", src)
+}
+
+// secretIn returns an error naming the first secret in the file at src,
+// unless it is skipped as binary. data is its content, unless it streams.
+func secretIn(src string, data []byte, streams bool, opts Options) error {
+	r := opts.redactor()
+	var findings []redact.Finding
+	if streams {
+		head, err := sniff(src)
+		if err != nil {
+			return nil // reported when the file is emitted
+		}
+		if binary, _ := detectBinary(head); binary {
+			return nil
+		}
+		findings, _ = streamFindings(src, r)
+	} else {
+		if binary, _ := detectBinary(data); binary {
+			return nil
+		}
+		_, findings = r.Redact(data)
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("%s:%d contains a secret (%s); remove it or use --on-secret redact to mask it",
+			src, findings[0].Line, findings[0].Type)
+	}
+	return nil
+}
+
+// secrets describes a number of secrets, e.g. "1 secret" or "3 secrets".
+func secrets(n int) string {
+	if n == 1 {
+		return "1 secret"
+	}
+	return fmt.Sprintf("%d secrets", n)
+}
+
+// distinct returns types without repeats, in order of first appearance.
+func distinct(types []string) []string {
+	var out []string
+	for _, t := range types {
+		if !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
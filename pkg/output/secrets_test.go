@@ -0,0 +1,69 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderSecrets verifies that secrets are redacted and sensitive files skipped by default, that error mode fails
+// before writing anything, and that off mode emits files as they are.
+func TestRenderSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := filepath.Join(tmpDir, "config.yaml")
+	env := filepath.Join(tmpDir, ".env")
+	clean := filepath.Join(tmpDir, "main.go")
+	require.NoError(t, os.WriteFile(config, []byte("user: admin\npassword: hunter22\n"), 0644))
+	require.NoError(t, os.WriteFile(env, []byte("TOKEN=x\n"), 0644))
+	require.NoError(t, os.WriteFile(clean, []byte("package main\n"), 0644))
+	files := []string{clean, config, env}
+	outPaths := []string{"main.go", "config.yaml", ".env"}
+
+	t.Run("redact", func(t *testing.T) {
+		var buf, warnings bytes.Buffer
+		stats, err := Render(context.Background(), &buf, files, outPaths, Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>", Warnings: &warnings})
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "<<<START:config.yaml>>>\nuser: admin\npassword: [REDACTED:password]\n")
+		assert.NotContains(t, buf.String(), "hunter22")
+		assert.NotContains(t, buf.String(), "TOKEN")
+		assert.Equal(t, []string{"password"}, stats.Files[1].Redacted)
+		assert.Equal(t, "sensitive", stats.Files[2].Skipped)
+		assert.Contains(t, warnings.String(), "Redacted 1 secret in "+config+" (password)\n")
+		assert.Contains(t, warnings.String(), "Skipping sensitive file "+env+"\n")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := Render(context.Background(), &buf, files[:2], outPaths[:2], Options{Secrets: SecretError})
+		assert.ErrorContains(t, err, config+":2 contains a secret (password)")
+		assert.Empty(t, buf.String())
+
+		_, err = Render(context.Background(), &buf, []string{clean, env}, outPaths, Options{Secrets: SecretError})
+		assert.ErrorContains(t, err, env+" is a sensitive file")
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("off", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := Render(context.Background(), &buf, files, outPaths, Options{Format: FormatJSONL, Secrets: SecretOff})
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "hunter22")
+		assert.Contains(t, buf.String(), "TOKEN=x")
+	})
+}
+
+// TestParseSecretMode verifies that --on-secret values map to modes and invalid values are rejected.
+func TestParseSecretMode(t *testing.T) {
+	for s, expected := range map[string]SecretMode{"redact": SecretRedact, "error": SecretError, "off": SecretOff} {
+		mode, err := ParseSecretMode(s)
+		require.NoError(t, err)
+		assert.Equal(t, expected, mode)
+	}
+	_, err := ParseSecretMode("warn")
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package output
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/matthewchivers/txt2llm/pkg/tokens"
+)
+
+// Stats summarises a Render call.
+type Stats struct {
+	// Files describes every input file, in input order. Callers may append
+	// files they left out before rendering, such as ignored ones.
+	Files []FileStats
+	// Written is the number of bytes written to the destination.
+	Written int64
+	// Tokens is the number of tokens written, including any preamble and
+	// delimiters; from Measure, the sum of the files' content tokens.
+	Tokens int
+}
+
+// FileStats describes what happened to one input file.
+type FileStats struct {
+	Source    string   // path the file was read from
+	Path      string   // path shown in the output
+	Bytes     int      // bytes of content emitted
+	Lines     int      // lines of content emitted
+	Tokens    int      // tokens in the content emitted, without delimiters
+	Truncated bool     // whether the content was cut short to fit the budget
+	Redacted  []string // type of each secret masked in the content
+	Skipped   string   // why the file was left out, "" if it was emitted
+}
+
+// Emitted returns the number of files that were written.
+func (s Stats) Emitted() int {
+	n := 0
+	for _, f := range s.Files {
+		if f.Skipped == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Measure reads each file as Render would and reports its bytes, lines and
+// content tokens without writing anything. It stops early if ctx is
+// cancelled.
+func Measure(ctx context.Context, files []string, outPaths []string, opts Options) (Stats, error) {
+	pf := prefetch(files, outPaths, opts, func(src, outPath string, opts Options) loaded {
+		return loaded{st: measure(src, outPath, opts)}
+	})
+	defer pf.stop()
+
+	var stats Stats
+	for i := range files {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		_, st := pf.get(i)
+		if st.Skipped == "" {
+			stats.Tokens += st.Tokens
+		}
+		stats.Files = append(stats.Files, st)
+	}
+	return stats, nil
+}
+
+// measure reads one file as Render would and returns its stats, with the
+// tokens of its content.
+func measure(src, outPath string, opts Options) FileStats {
+	counter := opts.Tokenizer
+	if counter == nil {
+		counter = tokens.Heuristic{}
+	}
+	secs, st := sections(src, outPath, opts)
+	switch {
+	case st.Skipped != "":
+	case secs[0].src != "":
+		out, err := copyStream(io.Discard, secs[0], opts, counter)
+		if err != nil {
+			warn(opts, "Error: %v\n", err)
+			st.Skipped = "unreadable"
+		}
+		st.Bytes, st.Lines, st.Tokens = out.bytes, out.lines, out.tokens
+		for _, f := range out.findings {
+			st.Redacted = append(st.Redacted, f.Type)
+		}
+	default:
+		for _, s := range secs {
+			st.Tokens += counter.Count(s.data)
+		}
+	}
+	return st
+}
+
+// WriteTable writes a report of s to w: emitted files sorted by the tokens
+// of their content, heaviest first, with their share of the total and, if
+// known, the tokens of the whole output, followed by skipped files and the
+// reason for each, and files whose secrets were redacted.
+func (s Stats) WriteTable(w io.Writer) {
+	var emitted, skipped []FileStats
+	for _, f := range s.Files {
+		if f.Skipped == "" {
+			emitted = append(emitted, f)
+		} else {
+			skipped = append(skipped, f)
+		}
+	}
+	slices.SortStableFunc(emitted, func(a, b FileStats) int {
+		return cmp.Or(cmp.Compare(b.Tokens, a.Tokens), cmp.Compare(a.Path, b.Path))
+	})
+
+	var total FileStats
+	width := len("TOTAL")
+	for _, f := range emitted {
+		total.Bytes += f.Bytes
+		total.Lines += f.Lines
+		total.Tokens += f.Tokens
+		width = max(width, len(f.Path))
+	}
+	share := func(tokens int) string {
+		if total.Tokens == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(tokens)/float64(total.Tokens))
+	}
+	row := func(path string, f FileStats) {
+		mark := ""
+		if f.Truncated {
+			mark = " (truncated)"
+		}
+		fmt.Fprintf(w, "%-*s %10d %8d %8d %7s%s\n", width, path, f.Bytes, f.Lines, f.Tokens, share(f.Tokens), mark)
+	}
+
+	fmt.Fprintf(w, "%-*s %10s %8s %8s %7s\n", width, "PATH", "BYTES", "LINES", "TOKENS", "SHARE")
+	for _, f := range emitted {
+		row(f.Path, f)
+	}
+	row("TOTAL", total)
+	if s.Tokens > 0 {
+		fmt.Fprintf(w, "\nTokens are of file content; the whole output, with delimiters, is %d tokens.\n", s.Tokens)
+	}
+
+	if len(skipped) > 0 {
+		fmt.Fprintf(w, "\nSkipped %d:\n", len(skipped))
+		for _, f := range skipped {
+			fmt.Fprintf(w, "  %s (%s)\n", f.Path, f.Skipped)
+		}
+	}
+
+	var redacted []FileStats
+	for _, f := range s.Files {
+		if len(f.Redacted) > 0 {
+			redacted = append(redacted, f)
+		}
+	}
+	if len(redacted) > 0 {
+		fmt.Fprintf(w, "\nRedacted %d:\n", len(redacted))
+		for _, f := range redacted {
+			fmt.Fprintf(w, "  %s (%s: %s)\n", f.Path, secrets(len(f.Redacted)), strings.Join(distinct(f.Redacted), ", "))
+		}
+	}
+}
+
+// countLines returns the number of lines in data, counting a final line
+// without a newline.
+func countLines(data []byte) int {
+	n := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
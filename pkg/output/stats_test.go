@@ -0,0 +1,46 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWriteTable verifies the report sorts files by tokens, shows each file's share and the tokens of the whole
+// output, and lists skipped files with their reasons and files with redacted secrets.
+func TestWriteTable(t *testing.T) {
+	stats := Stats{Files: []FileStats{
+		{Path: "small.go", Bytes: 40, Lines: 3, Tokens: 10, Redacted: []string{"password", "jwt", "password"}},
+		{Path: "logo.png", Skipped: "binary"},
+		{Path: "big.go", Bytes: 400, Lines: 30, Tokens: 30, Truncated: true},
+		{Path: "vendor/", Skipped: "ignored"},
+	}, Tokens: 52}
+
+	var buf bytes.Buffer
+	stats.WriteTable(&buf)
+
+	expected := "" +
+		"PATH          BYTES    LINES   TOKENS   SHARE\n" +
+		"big.go          400       30       30   75.0% (truncated)\n" +
+		"small.go         40        3       10   25.0%\n" +
+		"TOTAL           440       33       40  100.0%\n" +
+		"\n" +
+		"Tokens are of file content; the whole output, with delimiters, is 52 tokens.\n" +
+		"\n" +
+		"Skipped 2:\n" +
+		"  logo.png (binary)\n" +
+		"  vendor/ (ignored)\n" +
+		"\n" +
+		"Redacted 1:\n" +
+		"  small.go (3 secrets: password, jwt)\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+// TestCountLines verifies that a final line without a newline is counted.
+func TestCountLines(t *testing.T) {
+	assert.Equal(t, 0, countLines(nil))
+	assert.Equal(t, 1, countLines([]byte("one")))
+	assert.Equal(t, 2, countLines([]byte("one\ntwo\n")))
+	assert.Equal(t, 3, countLines([]byte("one\n\nthree")))
+}
@@ -0,0 +1,280 @@
+package output
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/matthewchivers/txt2llm/pkg/redact"
+	"github.com/matthewchivers/txt2llm/pkg/tokens"
+)
+
+const (
+	// streamChunk is how much of a streamed file is read at a time.
+	streamChunk = 1 << 20
+	// maxHold caps how much of a streamed file is held to finish a line or a
+	// private key block before a chunk is cut regardless.
+	maxHold = 8 * streamChunk
+)
+
+// streams reports whether srcPath is copied to the output in chunks rather
+// than read whole, and its size. Only files larger than opts.StreamOver are
+// streamed, and only in the marker format without a token budget, a diff
+// base or a custom reader, since the others need the whole content before
+// they can write any of it.
+func (opts Options) streams(srcPath string) (int64, bool) {
+	if opts.Format != FormatMarkers || opts.MaxTokens > 0 || opts.DiffBase != nil {
+		return 0, false
+	}
+	return opts.large(srcPath)
+}
+
+// large returns the size of srcPath if it is a regular file larger than
+// opts.StreamOver, read from disk rather than by a custom reader.
+func (opts Options) large(srcPath string) (int64, bool) {
+	if opts.StreamOver <= 0 || opts.ReadFile != nil {
+		return 0, false
+	}
+	info, err := os.Stat(srcPath)
+	if err != nil || !info.Mode().IsRegular() || info.Size() <= opts.StreamOver {
+		return 0, false
+	}
+	return info.Size(), true
+}
+
+// warnWhole notes on opts.Warnings that srcPath is read into memory whole
+// although it is larger than opts.StreamOver, because the format or options
+// rule out streaming it.
+func warnWhole(srcPath string, opts Options) {
+	if opts.Warnings == nil {
+		return
+	}
+	if _, ok := opts.streams(srcPath); ok {
+		return
+	}
+	if size, ok := opts.large(srcPath); ok {
+		warn(opts, "Reading %s (%s) into memory: large files only stream in the markers format without --max-tokens or --diff\n",
+			srcPath, humanSize(int(size)))
+	}
+}
+
+// streamSection applies the secret and binary policies to a file that is
+// streamed, reading only its start. It returns the section to emit, which
+// is copied from the file when written, or why the file is left out.
+func streamSection(srcPath, outPath string, size int64, opts Options) ([]section, FileStats) {
+	st := FileStats{Source: srcPath, Path: outPath}
+	if sensitive(srcPath, opts) {
+		st.Skipped = "sensitive"
+		return nil, st
+	}
+	head, err := sniff(srcPath)
+	if err != nil {
+		st.Skipped = "unreadable"
+		warn(opts, "Error reading %s: %v\n", srcPath, err)
+		return nil, st
+	}
+	s := section{path: outPath, src: srcPath, redact: opts.Secrets != SecretOff}
+	if binary, mime := detectBinary(head); binary {
+		switch opts.Binary {
+		case BinarySkip:
+			st.Skipped = "binary"
+			warn(opts, "Skipping binary file %s (%s)\n", srcPath, mime)
+			return nil, st
+		case BinaryPlaceholder:
+			data := []byte(placeholder(int(size), mime))
+			st.Bytes = len(data)
+			st.Lines = countLines(data)
+			return []section{{path: outPath, data: data}}, st
+		}
+		s.redact = false
+	}
+	st.Bytes = int(size)
+	return []section{s}, st
+}
+
+// sniff returns enough of the start of the file at path to classify it.
+func sniff(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen+1)
+	n, err := io.ReadFull(f, head)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+	return head[:n], err
+}
+
+// streamed describes the content copyStream wrote.
+type streamed struct {
+	bytes    int
+	lines    int
+	tokens   int
+	last     byte // the final byte written, if any
+	findings []redact.Finding
+}
+
+// copyStream copies the file behind s to w chunk by chunk, redacting
+// secrets if s.redact is set and counting tokens with counter. An error
+// part way through is returned with what was copied until then.
+func copyStream(w io.Writer, s section, opts Options, counter tokens.Counter) (streamed, error) {
+	var out streamed
+	f, err := os.Open(s.src)
+	if err != nil {
+		return out, err
+	}
+	defer f.Close()
+
+	c := chunkReader{r: f}
+	if s.redact {
+		c.redactor = opts.redactor()
+	}
+	for {
+		chunk, findings, err := c.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return out, fmt.Errorf("reading %s failed after %d bytes: %w", s.src, out.bytes, err)
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return out, err
+		}
+		out.bytes += len(chunk)
+		out.lines += bytes.Count(chunk, []byte("\n"))
+		out.tokens += counter.Count(chunk)
+		out.findings = append(out.findings, findings...)
+		if len(chunk) > 0 {
+			out.last = chunk[len(chunk)-1]
+		}
+	}
+	if out.bytes > 0 && out.last != '\n' {
+		out.lines++
+	}
+	return out, nil
+}
+
+// streamContains reports whether the file at path contains any of subs,
+// reading it in chunks. Chunks end at line breaks, so only text spanning
+// lines longer than maxHold can be missed.
+func streamContains(path string, subs ...string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	c := chunkReader{r: f}
+	for {
+		chunk, _, err := c.next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		for _, s := range subs {
+			if bytes.Contains(chunk, []byte(s)) {
+				return true, nil
+			}
+		}
+	}
+}
+
+// streamFindings returns the secrets in the file at path, reading it in
+// chunks and stopping after the first chunk that has any.
+func streamFindings(path string, r *redact.Redactor) ([]redact.Finding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c := chunkReader{r: f, redactor: r}
+	for {
+		_, findings, err := c.next()
+		if err == io.EOF || len(findings) > 0 {
+			return findings, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// chunkReader splits a file into chunks of about streamChunk bytes that end
+// at a line break where possible, redacting each if redactor is set. A
+// private key block is kept in one chunk so it is found whole.
+type chunkReader struct {
+	r        io.Reader
+	redactor *redact.Redactor
+	pending  []byte // read but not yet returned
+	eof      bool   // nothing more to read
+	err      error  // why reading stopped early, returned after pending
+	lines    int    // line breaks returned so far, to number findings
+}
+
+// next returns the next chunk and the secrets found in it, numbering their
+// lines from the start of the file. It returns io.EOF after the last chunk,
+// or the read error once everything read before it has been returned.
+func (c *chunkReader) next() ([]byte, []redact.Finding, error) {
+	want := streamChunk
+	for {
+		c.fill(want)
+		if len(c.pending) == 0 {
+			return nil, nil, cmp.Or(c.err, io.EOF)
+		}
+		cut := c.cut()
+		if cut == 0 {
+			want = len(c.pending) + streamChunk
+			continue
+		}
+
+		chunk := c.pending[:cut]
+		c.pending = slices.Clone(c.pending[cut:])
+		lines := bytes.Count(chunk, []byte("\n"))
+		var findings []redact.Finding
+		if c.redactor != nil {
+			chunk, findings = c.redactor.Redact(chunk)
+			for i := range findings {
+				findings[i].Line += c.lines
+			}
+		}
+		c.lines += lines
+		return chunk, findings, nil
+	}
+}
+
+// fill reads until at least want bytes are pending or reading stops.
+func (c *chunkReader) fill(want int) {
+	for !c.eof && len(c.pending) < want {
+		c.pending = slices.Grow(c.pending, want-len(c.pending))
+		n, err := c.r.Read(c.pending[len(c.pending):cap(c.pending)])
+		c.pending = c.pending[:len(c.pending)+n]
+		if err != nil {
+			c.eof = true
+			if err != io.EOF {
+				c.err = err
+			}
+		}
+	}
+}
+
+// cut returns how much of the pending bytes to return as the next chunk, or
+// 0 if more must be read first.
+func (c *chunkReader) cut() int {
+	if c.eof || len(c.pending) >= maxHold {
+		return len(c.pending)
+	}
+	cut := bytes.LastIndexByte(c.pending, '\n') + 1
+	if c.redactor != nil {
+		begin := bytes.LastIndex(c.pending[:cut], []byte("-----BEGIN"))
+		if begin >= 0 && !bytes.Contains(c.pending[begin:cut], []byte("-----END")) {
+			cut = begin
+		}
+	}
+	return cut
+}
@@ -0,0 +1,109 @@
+package output
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// treeNode is a file or directory in a file tree.
+type treeNode struct {
+	name      string
+	children  map[string]*treeNode
+	bytes     int
+	tokens    int
+	truncated bool
+}
+
+// buildTree renders an ASCII tree of the emitted files in files, rooted at
+// their deepest common directory, marking those that are truncated. If
+// annotate is set, every node is followed by its size and tokens before
+// truncation, directories summing their contents.
+func buildTree(files []FileStats, annotate bool) string {
+	var paths []string
+	for _, f := range files {
+		if f.Skipped == "" {
+			paths = append(paths, filepath.ToSlash(f.Path))
+		}
+	}
+	rootDir := commonDir(paths)
+
+	root := &treeNode{name: rootDir, children: map[string]*treeNode{}}
+	for _, f := range files {
+		if f.Skipped != "" {
+			continue
+		}
+		p := filepath.ToSlash(f.Path)
+		if rootDir != "." {
+			p = strings.TrimPrefix(strings.TrimPrefix(p, rootDir), "/")
+		}
+		node := root
+		node.bytes += f.Bytes
+		node.tokens += f.Tokens
+		for _, part := range strings.Split(p, "/") {
+			child, ok := node.children[part]
+			if !ok {
+				child = &treeNode{name: part, children: map[string]*treeNode{}}
+				node.children[part] = child
+			}
+			child.bytes += f.Bytes
+			child.tokens += f.Tokens
+			node = child
+		}
+		node.truncated = f.Truncated
+	}
+
+	var b strings.Builder
+	label := func(n *treeNode) string {
+		name := n.name
+		if len(n.children) > 0 && name != "." && !strings.HasSuffix(name, "/") {
+			name += "/"
+		}
+		switch {
+		case annotate && n.truncated:
+			name += fmt.Sprintf(" (%s, %d tokens, truncated)", humanSize(n.bytes), n.tokens)
+		case annotate:
+			name += fmt.Sprintf(" (%s, %d tokens)", humanSize(n.bytes), n.tokens)
+		case n.truncated:
+			name += " (truncated)"
+		}
+		return name
+	}
+	var walk func(n *treeNode, indent string)
+	walk = func(n *treeNode, indent string) {
+		names := make([]string, 0, len(n.children))
+		for name := range n.children {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for i, name := range names {
+			branch, next := "├── ", "│   "
+			if i == len(names)-1 {
+				branch, next = "└── ", "    "
+			}
+			child := n.children[name]
+			b.WriteString(indent + branch + label(child) + "\n")
+			walk(child, indent+next)
+		}
+	}
+	b.WriteString(label(root) + "\n")
+	walk(root, "")
+	return b.String()
+}
+
+// commonDir returns the deepest directory containing every path, or "." if
+// the paths are relative and share none.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return "."
+	}
+	dir := path.Dir(paths[0])
+	for _, p := range paths[1:] {
+		for dir != "." && dir != "/" && !strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuildTree verifies the tree layout, directory aggregation of annotations and that skipped files are left out.
+func TestBuildTree(t *testing.T) {
+	files := []FileStats{
+		{Path: "main.go", Bytes: 100, Tokens: 25},
+		{Path: "pkg/b/b.go", Bytes: 2048, Tokens: 500},
+		{Path: "pkg/a.go", Bytes: 1024, Tokens: 250},
+		{Path: "logo.png", Skipped: "binary"},
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		expected := "" +
+			".\n" +
+			"├── main.go\n" +
+			"└── pkg/\n" +
+			"    ├── a.go\n" +
+			"    └── b/\n" +
+			"        └── b.go\n"
+		assert.Equal(t, expected, buildTree(files, false))
+	})
+
+	t.Run("annotated", func(t *testing.T) {
+		expected := "" +
+			". (3.1 KiB, 775 tokens)\n" +
+			"├── main.go (100 B, 25 tokens)\n" +
+			"└── pkg/ (3.0 KiB, 750 tokens)\n" +
+			"    ├── a.go (1.0 KiB, 250 tokens)\n" +
+			"    └── b/ (2.0 KiB, 500 tokens)\n" +
+			"        └── b.go (2.0 KiB, 500 tokens)\n"
+		assert.Equal(t, expected, buildTree(files, true))
+	})
+
+	t.Run("absolute paths share a root", func(t *testing.T) {
+		abs := []FileStats{{Path: "/src/app/main.go"}, {Path: "/src/app/util/u.go"}}
+		expected := "/src/app/\n├── main.go\n└── util/\n    └── u.go\n"
+		assert.Equal(t, expected, buildTree(abs, false))
+	})
+}
+
+// TestRenderTree verifies the tree is placed between the header and the first file, and is supported by every
+// format except jsonl.
+func TestRenderTree(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "a.txt")
+	require.NoError(t, os.WriteFile(file, []byte("alpha\n"), 0644))
+	opts := Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>", Tree: true}
+
+	var buf bytes.Buffer
+	_, err := Render(context.Background(), &buf, []string{file}, []string{"src/a.txt"}, opts)
+	require.NoError(t, err)
+	header := "Each section below represents text output from one file.\n" +
+		"Delimiters: <<<START:{filename}>>> ... <<<END:{filename}>>>\n\n"
+	assert.True(t, strings.HasPrefix(buf.String(), header+"src/\n└── a.txt\n\n<<<START:src/a.txt>>>\n"))
+
+	buf.Reset()
+	opts.Format = FormatJSON
+	_, err = Render(context.Background(), &buf, []string{file}, []string{"src/a.txt"}, opts)
+	require.NoError(t, err)
+	var parsed struct {
+		Tree  string     `json:"tree"`
+		Files []jsonFile `json:"files"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &parsed))
+	assert.Equal(t, "src/\n└── a.txt\n", parsed.Tree)
+	assert.Len(t, parsed.Files, 1)
+
+	buf.Reset()
+	opts.Format = FormatJSONL
+	_, err = Render(context.Background(), &buf, []string{file}, []string{"src/a.txt"}, opts)
+	assert.Error(t, err)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// XML writes all files to w as a <documents> element with one <document> per
+// file, the structure Anthropic recommends for long-context prompts.
+func XML(w io.Writer, files []string, outPaths []string, opts Options) {
+	_, _ = render(context.Background(), w, files, outPaths, opts, xmlFormatter{})
+}
+
+// xmlFormatter writes Anthropic-style <documents>, starting with an optional
+// <revision> and <file_tree>. The revision is also a "revision" attribute of
+// each <source>.
+type xmlFormatter struct {
+	tree     string
+	revision string
+}
+
+func (f xmlFormatter) begin(w io.Writer) {
+	fmt.Fprintln(w, "<documents>")
+	if f.revision != "" {
+		fmt.Fprint(w, "<revision>")
+		_ = xml.EscapeText(w, []byte(f.revision))
+		fmt.Fprint(w, "</revision>\n")
+	}
+	if f.tree != "" {
+		fmt.Fprint(w, "<file_tree>\n")
+		_ = xml.EscapeText(w, []byte(f.tree))
+		fmt.Fprint(w, "</file_tree>\n")
+	}
+}
+
+func (f xmlFormatter) file(w io.Writer, index int, outPath string, data []byte) {
+	fmt.Fprintf(w, "<document index=\"%d\">\n<source", index)
+	if f.revision != "" {
+		fmt.Fprint(w, " revision=\"")
+		_ = xml.EscapeText(w, []byte(f.revision))
+		fmt.Fprint(w, "\"")
+	}
+	fmt.Fprint(w, ">")
+	_ = xml.EscapeText(w, []byte(outPath))
+	fmt.Fprintf(w, "</source>\n<document_content>\n")
+	data = xmlChars(data)
+	if needsCDATA(data) {
+		data = append(cdata(bytes.TrimSuffix(data, []byte("\n"))), '\n')
+	}
+	_, _ = w.Write(data)
+	newlineIfNeeded(w, data)
+	fmt.Fprintf(w, "</document_content>\n</document>\n")
+}
+
+func (xmlFormatter) end(w io.Writer) {
+	fmt.Fprintln(w, "</documents>")
+}
+
+// needsCDATA reports whether data contains characters that would make the
+// document invalid XML, or let it close its own element, if written verbatim.
+func needsCDATA(data []byte) bool {
+	return bytes.ContainsAny(data, "<&") || bytes.Contains(data, []byte("]]>"))
+}
+
+// xmlChars replaces what XML does not allow in a document even when escaped,
+// such as most control characters and invalid UTF-8, with U+FFFD, as
+// xml.EscapeText does. data is returned as it is if it is all allowed.
+func xmlChars(data []byte) []byte {
+	var buf []byte
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if (r != utf8.RuneError || size != 1) && xmlChar(r) {
+			if buf != nil {
+				buf = append(buf, data[i:i+size]...)
+			}
+		} else {
+			if buf == nil {
+				buf = append(make([]byte, 0, len(data)), data[:i]...)
+			}
+			buf = utf8.AppendRune(buf, utf8.RuneError)
+		}
+		i += size
+	}
+	if buf == nil {
+		return data
+	}
+	return buf
+}
+
+// xmlChar reports whether r is allowed in an XML 1.0 document.
+func xmlChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF || r >= 0xE000 && r <= 0xFFFD || r >= 0x10000 && r <= utf8.MaxRune
+}
+
+// cdata wraps data in a CDATA section, splitting any "]]>" in the content
+// across two sections so it cannot end the section early.
+func cdata(data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("<![CDATA[")
+	buf.Write(bytes.ReplaceAll(data, []byte("]]>"), []byte("]]]]><![CDATA[>")))
+	buf.WriteString("]]>")
+	return buf.Bytes()
+}
@@ -0,0 +1,103 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestXML verifies that XML output is well-formed and round-trips file contents, including content that would
+// otherwise close the document or a CDATA section early.
+func TestXML(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	contents := map[string]string{
+		"plain.txt":  "Hello, World!",
+		"tags.html":  "<p>a & b</p>\n</document_content>\n</document>\n",
+		"cdata.xml":  "<![CDATA[ x ]]> and again ]]>\n",
+		"a&b<c>.txt": "path needs escaping\n",
+		"ends.txt":   "a]]>b\n",
+	}
+	var files, outPaths []string
+	for _, name := range []string{"plain.txt", "tags.html", "cdata.xml", "a&b<c>.txt", "ends.txt"} {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents[name]), 0644))
+		files = append(files, path)
+		outPaths = append(outPaths, name)
+	}
+
+	var buf bytes.Buffer
+	XML(&buf, files, outPaths, Options{})
+	output := buf.String()
+
+	assert.True(t, strings.HasPrefix(output, "<documents>\n<document index=\"1\">\n<source>plain.txt</source>\n"))
+	assert.Contains(t, output, "<document_content>\nHello, World!\n</document_content>")
+	assert.True(t, strings.HasSuffix(output, "</document>\n</documents>\n"))
+
+	var parsed struct {
+		Documents []struct {
+			Index   int    `xml:"index,attr"`
+			Source  string `xml:"source"`
+			Content string `xml:"document_content"`
+		} `xml:"document"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+	require.Len(t, parsed.Documents, len(files))
+	for i, doc := range parsed.Documents {
+		assert.Equal(t, i+1, doc.Index)
+		assert.Equal(t, outPaths[i], doc.Source)
+		expected := contents[outPaths[i]]
+		if !strings.HasSuffix(expected, "\n") {
+			expected += "\n"
+		}
+		assert.Equal(t, "\n"+expected, doc.Content)
+	}
+}
+
+// TestXMLSkipsBinaryWithoutConsumingIndex verifies skipped files do not leave gaps in document indices.
+func TestXMLSkipsBinaryWithoutConsumingIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	bin := filepath.Join(tmpDir, "a.bin")
+	txt := filepath.Join(tmpDir, "b.txt")
+	require.NoError(t, os.WriteFile(bin, []byte("\x00\x01\x02"), 0644))
+	require.NoError(t, os.WriteFile(txt, []byte("text\n"), 0644))
+
+	var buf bytes.Buffer
+	XML(&buf, []string{bin, txt}, []string{"a.bin", "b.txt"}, Options{})
+
+	assert.Contains(t, buf.String(), "<document index=\"1\">\n<source>b.txt</source>")
+	assert.NotContains(t, buf.String(), "a.bin")
+}
+
+// TestCDATA verifies that "]]>" inside content is split across CDATA sections.
+func TestCDATA(t *testing.T) {
+	assert.False(t, needsCDATA([]byte("plain text")))
+	assert.True(t, needsCDATA([]byte("a < b")))
+	assert.True(t, needsCDATA([]byte("a & b")))
+	assert.True(t, needsCDATA([]byte("a]]>b")))
+	assert.Equal(t, "<![CDATA[a]]]]><![CDATA[>b]]>", string(cdata([]byte("a]]>b"))))
+}
+
+// TestXMLChars verifies that characters XML does not allow, even escaped, are replaced so the output stays
+// well-formed.
+func TestXMLChars(t *testing.T) {
+	plain := []byte("tab\tand caf\u00e9\r\n")
+	assert.Equal(t, plain, xmlChars(plain))
+	assert.Equal(t, "bell\uFFFD form feed\uFFFD bad \uFFFD\n", string(xmlChars([]byte("bell\x07 form feed\x0c bad \xff\n"))))
+
+	path := filepath.Join(t.TempDir(), "control.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\x1b[31mred\x1b[0m & more\n"), 0644))
+	var buf bytes.Buffer
+	XML(&buf, []string{path}, []string{"control.txt"}, Options{})
+	var parsed struct {
+		Content string `xml:"document>document_content"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+	assert.Equal(t, "\na\uFFFD[31mred\uFFFD[0m & more\n", parsed.Content)
+}
@@ -0,0 +1,260 @@
+// Package redact finds secrets in text and masks them before the text is
+// sent to a model.
+package redact
+
+import (
+	"bytes"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Rule detects one type of secret.
+type Rule struct {
+	// Type names the secret in reports and in its [REDACTED:type] mask.
+	Type string
+	// Pattern finds candidates. The first capturing group that matched is
+	// masked, or the whole match if the pattern has no groups.
+	Pattern *regexp.Regexp
+	// Valid, if set, rejects candidates that only look like secrets.
+	Valid func(secret string) bool
+	// Replace, if set, is used instead of the [REDACTED:type] mask.
+	Replace string
+	// Pseudonymise gives each distinct secret its own numbered mask, the
+	// same every time it is seen: {n} in Replace is the number, and the
+	// default mask becomes [REDACTED:type-{n}].
+	Pseudonymise bool
+}
+
+// Finding is one secret found in a text.
+type Finding struct {
+	Type string
+	Line int // 1-based line the secret starts on
+}
+
+// Redactor masks secrets and recognises files that should never be sent.
+type Redactor struct {
+	rules     []Rule
+	sensitive []string // file name globs that are never sent
+	allowed   []string // file name globs exempt from sensitive
+
+	mu         sync.Mutex
+	pseudonyms map[string]int // type and secret to its number
+	counts     map[string]int // numbers handed out per type
+}
+
+// secretKey matches assignment keys that usually hold a secret.
+const secretKey = `[\w.-]*(?:secret|token|api_?key|apikey|access_?key|auth|credential|private_?key)[\w.-]*`
+
+// passwordKey matches assignment keys that hold a password.
+const passwordKey = `[\w.-]*(?:password|passwd|pwd)[\w.-]*`
+
+// DefaultRules are the secrets New detects, most specific first.
+var DefaultRules = []Rule{
+	{Type: "private-key", Pattern: regexp.MustCompile(`-----BEGIN[ A-Z0-9]*PRIVATE KEY(?: BLOCK)?-----[\s\S]*?-----END[ A-Z0-9]*PRIVATE KEY(?: BLOCK)?-----`)},
+	{Type: "aws-access-key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{Type: "aws-secret-key", Pattern: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`)},
+	{Type: "github-token", Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{Type: "gitlab-token", Pattern: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{Type: "slack-token", Pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{Type: "stripe-key", Pattern: regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}`)},
+	{Type: "google-api-key", Pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{Type: "anthropic-key", Pattern: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{Type: "openai-key", Pattern: regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}`)},
+	{Type: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{Type: "password", Pattern: regexp.MustCompile(`(?i)` + passwordKey + `["']?\s*(?::=|=>|[:=])\s*["']([^"'\n]{4,})["']`)},
+	{
+		Type:    "password",
+		Pattern: regexp.MustCompile(`(?im)^[ \t]*(?:export[ \t]+)?` + passwordKey + `[ \t]*[:=][ \t]*([^\s"'#]{4,})[ \t]*$`),
+		Valid:   notCode,
+	},
+	{
+		Type:    "high-entropy-token",
+		Pattern: regexp.MustCompile(`(?i)` + secretKey + `["']?\s*(?::=|=>|[:=])\s*["']?([A-Za-z0-9+/=_.-]{16,})`),
+		Valid:   highEntropy,
+	},
+}
+
+// DefaultSensitive are file name globs New never lets through, and
+// DefaultAllowed the exceptions, which hold examples rather than secrets.
+var (
+	DefaultSensitive = []string{
+		".env*", "*.pem", "*.key", "*.p12", "*.pfx", "*.jks", "*.keystore",
+		"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519", ".netrc", ".pgpass",
+	}
+	DefaultAllowed = []string{".env.example", ".env.sample", ".env.template", ".env.dist"}
+)
+
+// New returns a Redactor using DefaultRules followed by rules, and
+// DefaultSensitive and DefaultAllowed.
+func New(rules ...Rule) *Redactor {
+	return &Redactor{
+		rules:      append(slices.Clip(DefaultRules), rules...),
+		sensitive:  DefaultSensitive,
+		allowed:    DefaultAllowed,
+		pseudonyms: map[string]int{},
+		counts:     map[string]int{},
+	}
+}
+
+// Sensitive reports whether the file at p should never be sent, judging by
+// its name alone.
+func (r *Redactor) Sensitive(p string) bool {
+	name := path.Base(strings.ReplaceAll(p, `\`, "/"))
+	return matchAny(r.sensitive, name) && !matchAny(r.allowed, name)
+}
+
+// Pseudonymises reports whether any rule numbers its secrets, so that the
+// masks depend on the order texts are redacted in.
+func (r *Redactor) Pseudonymises() bool {
+	return slices.ContainsFunc(r.rules, func(rule Rule) bool { return rule.Pseudonymise })
+}
+
+// Redact returns data with every secret replaced by its rule's mask, and what
+// was found, in the order the rules found it. Every rule looks at data as it
+// was passed in, so lines are counted in the original text, and a secret
+// overlapping one an earlier rule found is left to that rule. data is
+// returned unchanged if nothing was found. Pseudonyms stay the same across
+// calls.
+func (r *Redactor) Redact(data []byte) ([]byte, []Finding) {
+	var findings []Finding
+	var masked []span // sorted by start, never overlapping
+	var breaks []int  // offsets of line breaks, found when first needed
+	for _, rule := range r.rules {
+		for _, m := range rule.Pattern.FindAllSubmatchIndex(data, -1) {
+			start, end := secretSpan(m)
+			if start == end {
+				continue // nothing to mask
+			}
+			i, _ := slices.BinarySearchFunc(masked, start, func(s span, start int) int { return s.start - start })
+			if i > 0 && masked[i-1].end > start || i < len(masked) && masked[i].start < end {
+				continue
+			}
+			secret := string(data[start:end])
+			if strings.Contains(secret, "[REDACTED:") || rule.Valid != nil && !rule.Valid(secret) {
+				continue
+			}
+			if breaks == nil {
+				breaks = lineBreaks(data)
+			}
+			line, _ := slices.BinarySearch(breaks, start)
+			findings = append(findings, Finding{Type: rule.Type, Line: line + 1})
+			masked = slices.Insert(masked, i, span{start: start, end: end, mask: r.mask(rule, secret)})
+		}
+	}
+	if len(masked) == 0 {
+		return data, nil
+	}
+	var out bytes.Buffer
+	last := 0
+	for _, s := range masked {
+		out.Write(data[last:s.start])
+		out.WriteString(s.mask)
+		last = s.end
+	}
+	out.Write(data[last:])
+	return out.Bytes(), findings
+}
+
+// span is a secret at data[start:end] and what replaces it.
+type span struct {
+	start, end int
+	mask       string
+}
+
+// lineBreaks returns the offsets of the line breaks in data, plus one past
+// the end so the slice is never nil.
+func lineBreaks(data []byte) []int {
+	var breaks []int
+	for i, c := range data {
+		if c == '\n' {
+			breaks = append(breaks, i)
+		}
+	}
+	return append(breaks, len(data)+1)
+}
+
+// mask returns what replaces secret found by rule.
+func (r *Redactor) mask(rule Rule, secret string) string {
+	if !rule.Pseudonymise {
+		if rule.Replace == "" {
+			return "[REDACTED:" + rule.Type + "]"
+		}
+		return rule.Replace
+	}
+	replace := rule.Replace
+	if replace == "" {
+		replace = "[REDACTED:" + rule.Type + "-{n}]"
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := rule.Type + "\x00" + secret
+	n, ok := r.pseudonyms[key]
+	if !ok {
+		r.counts[rule.Type]++
+		n = r.counts[rule.Type]
+		r.pseudonyms[key] = n
+	}
+	return strings.ReplaceAll(replace, "{n}", strconv.Itoa(n))
+}
+
+// secretSpan returns the part of a match to mask: its first group that
+// matched, or all of it.
+func secretSpan(m []int) (int, int) {
+	for g := 2; g+1 < len(m); g += 2 {
+		if m[g] >= 0 {
+			return m[g], m[g+1]
+		}
+	}
+	return m[0], m[1]
+}
+
+// matchAny reports whether name matches any of the globs.
+func matchAny(globs []string, name string) bool {
+	for _, g := range globs {
+		if ok, _ := path.Match(g, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// codeLike matches values that are expressions rather than literals:
+// variables, templates and attribute or function references.
+var codeLike = regexp.MustCompile(`^(?:[$%<{]|[A-Za-z_]\w*(?:\.[A-Za-z_]\w*)+$)|\(`)
+
+// notCode reports whether an unquoted value is a literal rather than code.
+func notCode(value string) bool {
+	return !codeLike.MatchString(value)
+}
+
+// highEntropy reports whether value mixes letters and digits with enough
+// randomness to be a generated token rather than a word or identifier.
+func highEntropy(value string) bool {
+	letters, digits := false, false
+	counts := map[rune]int{}
+	for _, c := range value {
+		counts[c]++
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			letters = true
+		}
+	}
+	if !letters || !digits {
+		return false
+	}
+	entropy := 0.0
+	n := float64(len(value))
+	for _, count := range counts {
+		p := float64(count) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= 3.5
+}
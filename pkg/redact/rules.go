@@ -0,0 +1,72 @@
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rulesFile is the YAML form of a redaction rules file.
+type rulesFile struct {
+	Rules []struct {
+		Name         string `yaml:"name"`
+		Pattern      string `yaml:"pattern"`
+		Replace      string `yaml:"replace"`
+		Pseudonymise bool   `yaml:"pseudonymise"`
+	} `yaml:"rules"`
+}
+
+// LoadRules reads redaction rules from a YAML file of the form
+//
+//	rules:
+//	  - name: customer-id
+//	    pattern: '\bCUST-\d{6}\b'
+//	    pseudonymise: true
+//
+// where replace optionally overrides the mask.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(path, data)
+}
+
+// ParseRules parses the contents of a redaction rules file; name is used in
+// errors.
+func ParseRules(name string, data []byte) ([]Rule, error) {
+	var f rulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if len(f.Rules) == 0 {
+		return nil, fmt.Errorf("%s: no rules", name)
+	}
+
+	rules := make([]Rule, 0, len(f.Rules))
+	for i, r := range f.Rules {
+		switch {
+		case r.Name == "":
+			return nil, fmt.Errorf("%s: rule %d has no name", name, i+1)
+		case r.Pattern == "":
+			return nil, fmt.Errorf("%s: rule %s has no pattern", name, r.Name)
+		case r.Pseudonymise && r.Replace != "" && !strings.Contains(r.Replace, "{n}"):
+			return nil, fmt.Errorf("%s: rule %s is pseudonymised, so its replacement needs {n}", name, r.Name)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rule %s: %v", name, r.Name, err)
+		}
+		if re.MatchString("") {
+			return nil, fmt.Errorf("%s: rule %s matches empty text", name, r.Name)
+		}
+		rules = append(rules, Rule{Type: r.Name, Pattern: re, Replace: r.Replace, Pseudonymise: r.Pseudonymise})
+	}
+	return rules, nil
+}
@@ -0,0 +1,81 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// filter narrows resolved files using --include and --exclude patterns. A
+// file must match at least one include pattern (when any are given) and no
+// exclude pattern; exclude always wins over include.
+type filter struct {
+	include []string
+	exclude []string
+	cwd     string
+}
+
+// newFilter returns a filter for the given patterns, relative to the working
+// directory.
+func newFilter(include, exclude []string) filter {
+	cwd, _ := os.Getwd()
+	return filter{include: include, exclude: exclude, cwd: cwd}
+}
+
+// keep reports whether the absolute path abs passes the filter.
+func (f filter) keep(abs string) bool {
+	if len(f.include) == 0 && len(f.exclude) == 0 {
+		return true
+	}
+	rel := abs
+	if r, err := filepath.Rel(f.cwd, abs); err == nil {
+		rel = r
+	}
+	if len(f.include) > 0 && !matchAnyFilter(f.include, abs, rel) {
+		return false
+	}
+	return !matchAnyFilter(f.exclude, abs, rel)
+}
+
+// matchAnyFilter reports whether any of patterns matches the file.
+func matchAnyFilter(patterns []string, abs, rel string) bool {
+	for _, pat := range patterns {
+		if matchFilter(pat, abs, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFilter matches a filter pattern against a file. Patterns use the same
+// "**" and "{a,b}" syntax as inputs. Patterns containing a slash are matched
+// against the path relative to the working directory (or the absolute path,
+// for absolute patterns) and also match everything below a matching
+// directory. Patterns without a slash match any single path component, so
+// "*_test.go" or "node_modules" apply at every depth.
+func matchFilter(pattern, abs, rel string) bool {
+	target := filepath.ToSlash(rel)
+	if filepath.IsAbs(pattern) {
+		target = filepath.ToSlash(abs)
+	}
+	pattern = strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+	if pattern == "" {
+		return false
+	}
+	parts := strings.Split(target, "/")
+
+	if !strings.Contains(pattern, "/") {
+		for _, part := range parts {
+			if match(pattern, part) {
+				return true
+			}
+		}
+		return false
+	}
+	for i := len(parts); i > 0; i-- {
+		if match(pattern, strings.Join(parts[:i], "/")) {
+			return true
+		}
+	}
+	return false
+}
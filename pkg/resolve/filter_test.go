@@ -0,0 +1,128 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMatchFilter verifies that filter patterns match relative paths, directory prefixes and bare names at any depth.
+func TestMatchFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{name: "bare name matches basename", pattern: "*_test.go", rel: "src/a/a_test.go", want: true},
+		{name: "bare name matches directory component", pattern: "testdata", rel: "src/testdata/x.txt", want: true},
+		{name: "bare name does not match partially", pattern: "test", rel: "src/testdata/x.txt", want: false},
+		{name: "slash pattern anchored to cwd", pattern: "src/*.go", rel: "src/main.go", want: true},
+		{name: "slash pattern does not float", pattern: "src/*.go", rel: "lib/src/main.go", want: false},
+		{name: "slash pattern matches directory prefix", pattern: "src/gen", rel: "src/gen/a/b.go", want: true},
+		{name: "trailing slash matches directory", pattern: "vendor/", rel: "vendor/x/y.go", want: true},
+		{name: "dot slash prefix", pattern: "./src/**", rel: "src/a/b.go", want: true},
+		{name: "doublestar", pattern: "**/*.pb.go", rel: "api/v1/svc.pb.go", want: true},
+		{name: "braces", pattern: "**/*.{md,txt}", rel: "docs/a.txt", want: true},
+		{name: "no match", pattern: "**/*.md", rel: "docs/a.txt", want: false},
+		{name: "empty pattern", pattern: "", rel: "docs/a.txt", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchFilter(tt.pattern, "/abs/"+tt.rel, filepath.FromSlash(tt.rel)))
+		})
+	}
+
+	t.Run("absolute pattern matches absolute path", func(t *testing.T) {
+		abs := filepath.Join(string(filepath.Separator), "abs", "src", "a.go")
+		pattern := filepath.Join(string(filepath.Separator), "abs", "src", "*.go")
+		assert.True(t, matchFilter(pattern, abs, filepath.Join("src", "a.go")))
+	})
+}
+
+// TestFilesIncludeExclude verifies that Files applies include and exclude patterns after expansion, with exclude winning.
+func TestFilesIncludeExclude(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testFiles := map[string]string{
+		"README.md":             "# Readme",
+		"src/main.go":           "package main",
+		"src/main_test.go":      "package main",
+		"src/gen/api.pb.go":     "package gen",
+		"src/util/util.go":      "package util",
+		"src/util/util_test.go": "package util",
+		"docs/guide.md":         "# Guide",
+	}
+
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+
+	oldWd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmpDir))
+	defer func() { os.Chdir(oldWd) }()
+
+	tests := []struct {
+		name     string
+		patterns []string
+		opts     Options
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "exclude tests and generated code",
+			patterns: []string{"src"},
+			opts:     Options{Recursive: true, Exclude: []string{"*_test.go", "src/gen"}},
+			expected: []string{"src/main.go", "src/util/util.go"},
+		},
+		{
+			name:     "include narrows results",
+			patterns: []string{"."},
+			opts:     Options{Recursive: true, Include: []string{"**/*.md"}},
+			expected: []string{"README.md", "docs/guide.md"},
+		},
+		{
+			name:     "exclude wins over include",
+			patterns: []string{"."},
+			opts:     Options{Recursive: true, Include: []string{"src/**"}, Exclude: []string{"*_test.go"}},
+			expected: []string{"src/main.go", "src/gen/api.pb.go", "src/util/util.go"},
+		},
+		{
+			name:     "filters apply to explicit files",
+			patterns: []string{"src/main.go", "src/main_test.go"},
+			opts:     Options{Exclude: []string{"*_test.go"}},
+			expected: []string{"src/main.go"},
+		},
+		{
+			name:     "filtering everything out is an error",
+			patterns: []string{"docs"},
+			opts:     Options{Exclude: []string{"*.md"}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Files(tt.patterns, tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var expected []string
+			for _, p := range tt.expected {
+				abs, _ := filepath.Abs(p)
+				expected = append(expected, abs)
+			}
+			assert.ElementsMatch(t, expected, files)
+		})
+	}
+}
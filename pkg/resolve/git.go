@@ -0,0 +1,361 @@
+package resolve
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Git selects files by asking the local git binary, instead of walking
+// directories. Paths come from the repository containing the working
+// directory and are limited to files below it. When more than one source is
+// set, files from any of them are selected.
+type Git struct {
+	// Tracked selects every file in the index.
+	Tracked bool
+	// Changed, if set, selects files that differ from the merge base of this
+	// revision and HEAD, including uncommitted and untracked files.
+	Changed string
+	// Staged selects files with staged changes.
+	Staged bool
+}
+
+// Enabled reports whether any git source is set.
+func (g Git) Enabled() bool {
+	return g.Tracked || g.Changed != "" || g.Staged
+}
+
+// String describes the selection for messages, e.g. "changed (since main)".
+func (g Git) String() string {
+	var parts []string
+	if g.Tracked {
+		parts = append(parts, "tracked")
+	}
+	if g.Changed != "" {
+		parts = append(parts, fmt.Sprintf("changed (since %s)", g.Changed))
+	}
+	if g.Staged {
+		parts = append(parts, "staged")
+	}
+	return strings.Join(parts, " or ")
+}
+
+// RevisionPatterns returns the patterns that name a git revision and no
+// path, if Changed is HEAD. That is the base when --git-changed has no
+// value, so "--git-changed main" takes main as a pattern, and such a
+// pattern was probably meant as the base.
+func (g Git) RevisionPatterns(patterns []string) []string {
+	if g.Changed != "HEAD" {
+		return nil
+	}
+	var revs []string
+	for _, pat := range patterns {
+		if _, err := os.Lstat(pat); err == nil {
+			continue
+		}
+		if _, err := DescribeRevision(pat); err == nil {
+			revs = append(revs, pat)
+		}
+	}
+	return revs
+}
+
+// files returns the paths git selects, relative to the working directory.
+// Deleted files are left out.
+func (g Git) files() ([]string, error) {
+	if g.Changed != "" {
+		if err := checkRevision(g.Changed); err != nil {
+			return nil, err
+		}
+	}
+	var cmds [][]string
+	if g.Tracked {
+		cmds = append(cmds, []string{"ls-files", "-z"})
+	}
+	if g.Changed != "" {
+		cmds = append(cmds,
+			[]string{"diff", "--name-only", "--relative", "--diff-filter=d", "-z", "--merge-base", g.Changed},
+			[]string{"ls-files", "--others", "--exclude-standard", "-z"})
+	}
+	if g.Staged {
+		cmds = append(cmds, []string{"diff", "--cached", "--name-only", "--relative", "--diff-filter=d", "-z"})
+	}
+
+	var paths []string
+	for _, args := range cmds {
+		out, err := runGit(args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range strings.Split(out, "\x00") {
+			if p != "" {
+				paths = append(paths, filepath.FromSlash(p))
+			}
+		}
+	}
+	return paths, nil
+}
+
+// runGit runs git with args in the working directory and returns its output.
+// Messages are in English whatever the user's locale, so they can be matched.
+func runGit(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// checkRevision rejects a revision that git would take as an option, since
+// revisions can come from a repository's own configuration file.
+func checkRevision(rev string) error {
+	if strings.HasPrefix(rev, "-") {
+		return fmt.Errorf("invalid revision %q: it must not start with -", rev)
+	}
+	return nil
+}
+
+// revisionFiles returns the regular files in rev below the working
+// directory, relative to it. Symlinks and submodules are left out.
+func revisionFiles(rev string) ([]string, error) {
+	if err := checkRevision(rev); err != nil {
+		return nil, err
+	}
+	out, err := runGit("ls-tree", "-r", "-z", rev)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range strings.Split(out, "\x00") {
+		// Each entry is "<mode> <type> <object>\t<path>".
+		meta, path, ok := strings.Cut(entry, "\t")
+		if fields := strings.Fields(meta); ok && len(fields) == 3 && fields[1] == "blob" && fields[0] != "120000" {
+			paths = append(paths, filepath.FromSlash(path))
+		}
+	}
+	return paths, nil
+}
+
+// RevisionReader reads files as they are in one git revision through a
+// single "git cat-file --batch", instead of starting git for every file. It
+// is safe for concurrent use.
+type RevisionReader struct {
+	rev string
+	cwd string
+
+	mu  sync.Mutex
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+// OpenRevision starts a RevisionReader for the git revision rev. Close it
+// when done.
+func OpenRevision(rev string) (*RevisionReader, error) {
+	if err := checkRevision(rev); err != nil {
+		return nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	return &RevisionReader{rev: rev, cwd: cwd, cmd: cmd, in: in, out: bufio.NewReader(out)}, nil
+}
+
+// ReadFile returns the content of the file at path, absolute or relative to
+// the working directory and below it, as it is in the revision. The error
+// wraps os.ErrNotExist if the revision has no such file.
+func (r *RevisionReader) ReadFile(path string) ([]byte, error) {
+	abs := path
+	if !filepath.IsAbs(path) {
+		abs = filepath.Join(r.cwd, path)
+	}
+	if !within(r.cwd, abs) {
+		return nil, fmt.Errorf("%s is not below the working directory %s", path, r.cwd)
+	}
+	rel, err := filepath.Rel(r.cwd, abs)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(rel, "\n\r") {
+		return nil, fmt.Errorf("%s cannot be read from git: its name has a line break", path)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := fmt.Fprintf(r.in, "%s:./%s\n", r.rev, filepath.ToSlash(rel)); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	// The reply is "<object> <type> <size>" and the content, then a newline,
+	// or "<name> missing".
+	header, err := r.out.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		if strings.HasSuffix(header, " missing\n") {
+			return nil, fmt.Errorf("%s is not in revision %s: %w", rel, r.rev, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("git cat-file: unexpected reply %q", strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: unexpected reply %q", strings.TrimSpace(header))
+	}
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(r.out, data); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	if fields[1] != "blob" {
+		return nil, fmt.Errorf("%s is a %s in revision %s, not a file", rel, fields[1], r.rev)
+	}
+	return data[:size], nil
+}
+
+// Close stops the git process.
+func (r *RevisionReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.in.Close()
+	return r.cmd.Wait()
+}
+
+// DescribeRevision returns rev with the abbreviated commit it names, e.g.
+// "v1.2 (3f2a9c1)", or just the abbreviation if rev is a commit hash.
+func DescribeRevision(rev string) (string, error) {
+	if err := checkRevision(rev); err != nil {
+		return "", err
+	}
+	out, err := runGit("rev-parse", "--verify", "--short", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	short := strings.TrimSpace(out)
+	if strings.HasPrefix(rev, short) || strings.HasPrefix(short, rev) {
+		return short, nil
+	}
+	return fmt.Sprintf("%s (%s)", rev, short), nil
+}
+
+// gitFiles adds the regular files that g selects, or that rev contains when
+// it is set, which match patterns, or all of them when there are no
+// patterns. It returns a description of the selection for messages. A
+// pattern that names a file selects it, and one that names a directory
+// selects the files in it, or everything below it if recursive is set, as
+// when walking the working tree; other patterns match like --include. Git has already applied
+// gitignore rules, so only .txt2llmignore files are honoured, for the file
+// and each directory above it.
+func gitFiles(g Git, rev string, patterns []string, recursive bool, ign *ignorer, add func(string)) (string, error) {
+	var paths []string
+	var err error
+	what := g.String() + " files"
+	switch {
+	case rev != "" && g.Enabled():
+		return "", fmt.Errorf("a revision cannot be combined with selecting %s", what)
+	case rev != "":
+		what = "files in revision " + rev
+		paths, err = revisionFiles(rev)
+	default:
+		paths, err = g.files()
+	}
+	if err != nil {
+		return "", err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for _, rel := range paths {
+		abs := filepath.Join(cwd, rel)
+		if rev == "" {
+			if info, err := os.Stat(abs); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+		}
+		if len(patterns) > 0 && !selected(patterns, abs, rel, recursive) {
+			continue
+		}
+		if ignoredBelow(ign, cwd, abs) {
+			continue
+		}
+		add(abs)
+	}
+	return what, nil
+}
+
+// selected reports whether any pattern selects the file. A directory
+// selects only the files directly in it unless recursive is set.
+func selected(patterns []string, abs, rel string, recursive bool) bool {
+	for _, pat := range patterns {
+		if pat == "" {
+			continue
+		}
+		if info, err := os.Stat(pat); err == nil {
+			p, err := filepath.Abs(pat)
+			if err != nil {
+				continue
+			}
+			if p == abs || info.IsDir() && (filepath.Dir(abs) == p || recursive && within(p, abs)) {
+				return true
+			}
+			continue
+		}
+		if matchFilter(pat, abs, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredBelow reports whether ign ignores abs or any directory between cwd
+// and it.
+func ignoredBelow(ign *ignorer, cwd, abs string) bool {
+	rel, err := filepath.Rel(cwd, filepath.Dir(abs))
+	if err != nil {
+		return ign.ignored(abs, false)
+	}
+	dir := cwd
+	if rel != "." {
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			dir = filepath.Join(dir, part)
+			if ign.ignored(dir, true) {
+				return true
+			}
+		}
+	}
+	return ign.ignored(abs, false)
+}
+
+// within reports whether path is dir or below it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
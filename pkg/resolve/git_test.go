@@ -0,0 +1,243 @@
+package resolve
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFilesFromGit verifies tracked, changed and staged selections and revisions, narrowed by patterns and filters,
+// in a real repository.
+func TestFilesFromGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	git("init", "-q", "-b", "main")
+	write("main.go", "package main\n")
+	write("pkg/a.go", "package pkg\n")
+	write("pkg/a_test.go", "package pkg\n")
+	write("pkg/sub/b.go", "package sub\n")
+	write("docs/readme.md", "# docs\n")
+	write("gone.txt", "bye\n")
+	write(".gitignore", "*.log\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	git("checkout", "-q", "-b", "feature")
+	write("pkg/a.go", "package pkg // committed on the branch\n")
+	git("commit", "-q", "-am", "branch change")
+	write("docs/readme.md", "# changed\n")
+	write("pkg/new.go", "package pkg\n")
+	write("debug.log", "ignored\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "gone.txt")))
+	write("main.go", "package main // staged\n")
+	git("add", "main.go")
+
+	oldWd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer func() { os.Chdir(oldWd) }()
+
+	tests := []struct {
+		name     string
+		patterns []string
+		opts     Options
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:     "tracked",
+			opts:     Options{Git: Git{Tracked: true}},
+			expected: []string{".gitignore", "docs/readme.md", "main.go", "pkg/a.go", "pkg/a_test.go", "pkg/sub/b.go"},
+		},
+		{
+			name:     "changed since HEAD",
+			opts:     Options{Git: Git{Changed: "HEAD"}},
+			expected: []string{"docs/readme.md", "main.go", "pkg/new.go"},
+		},
+		{
+			name:     "changed since branch point",
+			opts:     Options{Git: Git{Changed: "main"}},
+			expected: []string{"docs/readme.md", "main.go", "pkg/a.go", "pkg/new.go"},
+		},
+		{
+			name:     "staged",
+			opts:     Options{Git: Git{Staged: true}},
+			expected: []string{"main.go"},
+		},
+		{
+			name:     "patterns narrow the selection",
+			patterns: []string{"pkg", "*.md"},
+			opts:     Options{Git: Git{Tracked: true}},
+			expected: []string{"docs/readme.md", "pkg/a.go", "pkg/a_test.go"},
+		},
+		{
+			name:     "recursive patterns",
+			patterns: []string{"pkg"},
+			opts:     Options{Git: Git{Tracked: true}, Recursive: true},
+			expected: []string{"pkg/a.go", "pkg/a_test.go", "pkg/sub/b.go"},
+		},
+		{
+			name:     "exclude applies",
+			opts:     Options{Git: Git{Tracked: true}, Exclude: []string{"*_test.go", "docs"}},
+			expected: []string{".gitignore", "main.go", "pkg/a.go", "pkg/sub/b.go"},
+		},
+		{
+			name:     "nothing matches",
+			patterns: []string{"*.rs"},
+			opts:     Options{Git: Git{Staged: true}},
+			wantErr:  "no staged files matched any of the patterns: [*.rs]",
+		},
+		{
+			name:     "revision",
+			opts:     Options{Rev: "main"},
+			expected: []string{".gitignore", "docs/readme.md", "gone.txt", "main.go", "pkg/a.go", "pkg/a_test.go", "pkg/sub/b.go"},
+		},
+		{
+			name:     "revision narrowed by patterns",
+			patterns: []string{"gone.txt", "pkg"},
+			opts:     Options{Rev: "main", Exclude: []string{"*_test.go"}},
+			expected: []string{"gone.txt", "pkg/a.go"},
+		},
+		{
+			name:     "recursive revision",
+			patterns: []string{"pkg"},
+			opts:     Options{Rev: "main", Recursive: true, Exclude: []string{"*_test.go"}},
+			expected: []string{"pkg/a.go", "pkg/sub/b.go"},
+		},
+		{
+			name:    "revision with a git selection",
+			opts:    Options{Rev: "main", Git: Git{Staged: true}},
+			wantErr: "a revision cannot be combined with selecting staged files",
+		},
+		{
+			name:    "option as changed base",
+			opts:    Options{Git: Git{Changed: "--output=" + filepath.Join(dir, "main.go")}},
+			wantErr: "must not start with -",
+		},
+		{
+			name:    "option as revision",
+			opts:    Options{Rev: "--output=" + filepath.Join(dir, "main.go")},
+			wantErr: "must not start with -",
+		},
+		{
+			name:    "bad revision",
+			opts:    Options{Git: Git{Changed: "no-such-branch"}},
+			wantErr: "git diff:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Files(tt.patterns, tt.opts)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			var rel []string
+			for _, f := range files {
+				r, err := filepath.Rel(dir, f)
+				require.NoError(t, err)
+				rel = append(rel, filepath.ToSlash(r))
+			}
+			sort.Strings(rel)
+			assert.Equal(t, tt.expected, rel)
+		})
+	}
+
+	t.Run("read at revision", func(t *testing.T) {
+		main, err := OpenRevision("main")
+		require.NoError(t, err)
+		data, err := main.ReadFile(filepath.Join(dir, "pkg", "a.go"))
+		require.NoError(t, err)
+		assert.Equal(t, "package pkg\n", string(data))
+
+		_, err = main.ReadFile("pkg/new.go")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = main.ReadFile("pkg")
+		assert.ErrorContains(t, err, "is a tree in revision main")
+		_, err = main.ReadFile(filepath.Join("..", "outside.txt"))
+		assert.ErrorContains(t, err, "is not below the working directory")
+		_, err = main.ReadFile(filepath.Dir(dir))
+		assert.ErrorContains(t, err, "is not below the working directory")
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				data, err := main.ReadFile("main.go")
+				assert.NoError(t, err)
+				assert.Equal(t, "package main\n", string(data))
+			}()
+		}
+		wg.Wait()
+		require.NoError(t, main.Close())
+
+		feature, err := OpenRevision("feature")
+		require.NoError(t, err)
+		data, err = feature.ReadFile("gone.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "bye\n", string(data))
+		require.NoError(t, feature.Close())
+
+		name, err := DescribeRevision("main")
+		require.NoError(t, err)
+		assert.Regexp(t, `^main \([0-9a-f]{7,}\)$`, name)
+		short := name[len("main (") : len(name)-1]
+		name, err = DescribeRevision(short)
+		require.NoError(t, err)
+		assert.Equal(t, short, name)
+
+		assert.Equal(t, []string{"main"}, Git{Changed: "HEAD"}.RevisionPatterns([]string{"main", "pkg", "*.go", "no-such"}))
+		assert.Empty(t, Git{Changed: "main"}.RevisionPatterns([]string{"feature"}), "only a base left to default")
+
+		_, err = OpenRevision("--output=x")
+		assert.ErrorContains(t, err, "must not start with -")
+		_, err = DescribeRevision("--output=x")
+		assert.ErrorContains(t, err, "must not start with -")
+		data, err = os.ReadFile(filepath.Join(dir, "main.go"))
+		require.NoError(t, err)
+		assert.Equal(t, "package main // staged\n", string(data), "an option-like revision must not reach git")
+	})
+
+	t.Run("txt2llmignore applies to directories above files", func(t *testing.T) {
+		write(".txt2llmignore", "docs/\n")
+		defer os.Remove(filepath.Join(dir, ".txt2llmignore"))
+		var ignored []string
+		files, err := Files(nil, Options{Git: Git{Changed: "HEAD"}, Ignored: func(p string) { ignored = append(ignored, p) }})
+		require.NoError(t, err)
+		assert.NotContains(t, files, filepath.Join(dir, "docs", "readme.md"))
+		assert.Contains(t, ignored, filepath.Join(dir, "docs")+string(filepath.Separator))
+	})
+}
+
+// TestGitString verifies how selections are described in messages.
+func TestGitString(t *testing.T) {
+	assert.False(t, Git{}.Enabled())
+	assert.Equal(t, "tracked or changed (since main)", Git{Tracked: true, Changed: "main"}.String())
+}
@@ -0,0 +1,189 @@
+package resolve
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// glob expands pat to the paths it matches. Unlike filepath.Glob it supports
+// "**" (zero or more directories) and "{a,b}" alternation, so results do not
+// depend on the invoking shell. Returned paths share pat's base directory.
+// Directories and files below the base that ign ignores are skipped.
+func glob(pat string, ign *ignorer) []string {
+	var out []string
+	for _, alt := range expandBraces(filepath.ToSlash(pat)) {
+		out = append(out, globOne(alt, ign)...)
+	}
+	return out
+}
+
+// globOne expands a single brace-free, slash-separated pattern.
+func globOne(pat string, ign *ignorer) []string {
+	base, segs := splitBase(pat)
+	root := filepath.FromSlash(base)
+	if len(segs) == 0 {
+		if _, err := os.Lstat(root); err != nil {
+			return nil
+		}
+		return []string{root}
+	}
+	deep := false
+	for _, s := range segs {
+		if s == "**" {
+			deep = true
+			break
+		}
+	}
+
+	var out []string
+	_ = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil || p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if d.IsDir() {
+			if (!deep && len(parts) >= len(segs)) || ign.ignored(p, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matchSegments(segs, parts) && !ign.ignored(p, false) {
+			out = append(out, p)
+		}
+		return nil
+	})
+	return out
+}
+
+// splitBase splits pat into its longest leading run of literal directories and
+// the remaining pattern segments. A pattern without metacharacters yields no
+// segments.
+func splitBase(pat string) (string, []string) {
+	segs := strings.Split(pat, "/")
+	i := 0
+	for i < len(segs) && !hasMeta(segs[i]) {
+		i++
+	}
+	if i == len(segs) {
+		return pat, nil
+	}
+	base := strings.Join(segs[:i], "/")
+	switch {
+	case base == "" && strings.HasPrefix(pat, "/"):
+		base = "/"
+	case base == "":
+		base = "."
+	}
+	return base, segs[i:]
+}
+
+// hasMeta reports whether s contains any glob metacharacters.
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[{\`)
+}
+
+// match reports whether the slash-separated name matches pattern, honouring
+// "**" and "{a,b}" in the same way as glob.
+func match(pattern, name string) bool {
+	parts := strings.Split(name, "/")
+	for _, alt := range expandBraces(pattern) {
+		if matchSegments(strings.Split(alt, "/"), parts) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches name segments against pattern segments. A "**"
+// segment matches zero or more whole segments, except when it ends the
+// pattern, where it must match at least one.
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for len(pat) > 1 && pat[1] == "**" {
+				pat = pat[1:]
+			}
+			if len(pat) == 1 {
+				return len(name) > 0
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pat[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// expandBraces expands "{a,b}" alternations, including nested ones, into the
+// full list of alternative patterns. Unbalanced braces are kept literally.
+func expandBraces(pat string) []string {
+	start, end := -1, -1
+	depth := 0
+	for i := 0; i < len(pat) && end < 0; i++ {
+		switch pat[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if start < 0 || end < 0 {
+		return []string{pat}
+	}
+
+	var out []string
+	prefix, suffix := pat[:start], pat[end+1:]
+	for _, alt := range splitAlternatives(pat[start+1 : end]) {
+		out = append(out, expandBraces(prefix+alt+suffix)...)
+	}
+	return out
+}
+
+// splitAlternatives splits the body of a brace group on top-level commas.
+func splitAlternatives(body string) []string {
+	var out []string
+	depth, last := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, body[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(out, body[last:])
+}
@@ -0,0 +1,151 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGlob verifies that glob expands "**" across directory levels and "{a,b}" alternations without relying on a shell.
+func TestGlob(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testFiles := map[string]string{
+		"main.go":              "package main",
+		"README.md":            "# Readme",
+		"notes.txt":            "notes",
+		"pkg/a/a.go":           "package a",
+		"pkg/a/a_test.go":      "package a",
+		"pkg/b/deep/b.go":      "package b",
+		"docs/guide.md":        "# Guide",
+		"docs/api/index.rst":   "Index",
+		"docs/api/changes.txt": "changes",
+	}
+
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+
+	oldWd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmpDir))
+	defer func() { os.Chdir(oldWd) }()
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{
+			name:     "doublestar all go files",
+			pattern:  "**/*.go",
+			expected: []string{"main.go", "pkg/a/a.go", "pkg/a/a_test.go", "pkg/b/deep/b.go"},
+		},
+		{
+			name:     "doublestar under base directory",
+			pattern:  "pkg/**/*.go",
+			expected: []string{"pkg/a/a.go", "pkg/a/a_test.go", "pkg/b/deep/b.go"},
+		},
+		{
+			name:     "doublestar in the middle",
+			pattern:  "pkg/**/deep/*.go",
+			expected: []string{"pkg/b/deep/b.go"},
+		},
+		{
+			name:     "trailing doublestar",
+			pattern:  "docs/**",
+			expected: []string{"docs/guide.md", "docs/api/index.rst", "docs/api/changes.txt"},
+		},
+		{
+			name:     "brace alternation",
+			pattern:  "**/*.{md,txt,rst}",
+			expected: []string{"README.md", "notes.txt", "docs/guide.md", "docs/api/index.rst", "docs/api/changes.txt"},
+		},
+		{
+			name:     "brace alternation of directories",
+			pattern:  "{pkg/a,docs}/*",
+			expected: []string{"pkg/a/a.go", "pkg/a/a_test.go", "docs/guide.md"},
+		},
+		{
+			name:     "single star stays in one directory",
+			pattern:  "pkg/*/*.go",
+			expected: []string{"pkg/a/a.go", "pkg/a/a_test.go"},
+		},
+		{
+			name:     "literal path",
+			pattern:  "docs/guide.md",
+			expected: []string{"docs/guide.md"},
+		},
+		{
+			name:     "no matches",
+			pattern:  "**/*.xyz",
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var expected []string
+			for _, p := range tt.expected {
+				expected = append(expected, filepath.FromSlash(p))
+			}
+			assert.ElementsMatch(t, expected, glob(tt.pattern, nil))
+		})
+	}
+}
+
+// TestMatch verifies that match applies "**", single-segment wildcards and brace alternation to slash-separated paths.
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "pkg/a/main.go", true},
+		{"pkg/**", "pkg", false},
+		{"pkg/**", "pkg/a/b.go", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/y/c", false},
+		{"*.{go,md}", "README.md", true},
+		{"*.{go,md}", "notes.txt", false},
+		{"{a,b/{c,d}}/x", "b/d/x", true},
+		{"file?.txt", "file1.txt", true},
+		{"file[0-9].txt", "filea.txt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, match(tt.pattern, tt.name))
+		})
+	}
+}
+
+// TestExpandBraces verifies brace expansion handles nesting, escapes and unbalanced input.
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"*.go", []string{"*.go"}},
+		{"*.{md,txt}", []string{"*.md", "*.txt"}},
+		{"{a,b}/{c,d}", []string{"a/c", "a/d", "b/c", "b/d"}},
+		{"x{a,b{c,d}}", []string{"xa", "xbc", "xbd"}},
+		{"unbalanced{a,b", []string{"unbalanced{a,b"}},
+		{`lit\{a,b}`, []string{`lit\{a,b}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.expected, expandBraces(tt.pattern))
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package resolve
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// rule is a single compiled line from a gitignore-style file.
+type rule struct {
+	base     string // directory the pattern is relative to
+	pattern  string // slash-separated pattern without negation or trailing slash
+	negate   bool   // "!pattern" re-includes previously ignored paths
+	dirOnly  bool   // "pattern/" only matches directories
+	anchored bool   // patterns containing a slash match from base, others match any basename
+}
+
+// matches reports whether the rule applies to path.
+func (r rule) matches(path string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	rel, err := filepath.Rel(r.base, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false
+	}
+	if r.anchored {
+		return match(r.pattern, rel)
+	}
+	return match(r.pattern, rel[strings.LastIndex(rel, "/")+1:])
+}
+
+// parseRules compiles the lines of a gitignore-style file whose patterns are
+// relative to base.
+func parseRules(data, base string) []rule {
+	var rules []rule
+	sc := bufio.NewScanner(strings.NewReader(data))
+	for sc.Scan() {
+		if r, ok := parseRule(sc.Text(), base); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// parseRule compiles one gitignore line, reporting false for blanks and comments.
+func parseRule(line, base string) (rule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	r := rule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+	r.pattern = line
+	return r, true
+}
+
+// Names of the per-directory ignore files honoured by ignorer.
+const (
+	gitignoreFile     = ".gitignore"
+	txt2llmignoreFile = ".txt2llmignore"
+)
+
+// ignorer decides whether walked paths are excluded by ignore rules. Rules
+// are loaded lazily and cached, so one ignorer should be shared across a whole
+// resolution. A nil ignorer ignores nothing.
+//
+// .txt2llmignore files are always honoured: every one from the repository root
+// (or the filesystem root outside a repository) down to a path's directory,
+// plus the one in the working directory. When git is set, .gitignore files,
+// .git/info/exclude and the global excludes file also apply inside
+// repositories, and .git directories are skipped.
+type ignorer struct {
+	git       bool
+	cwd       string
+	fileRules map[string][]rule // ignore file path -> its rules
+	repoRoots map[string]string // directory -> enclosing repository root, "" if none
+	repoRules map[string][]rule // repository root -> global and info/exclude rules
+	global    *string           // global excludes file, resolved once
+	report    func(path string) // called with each ignored path, if set
+}
+
+// newIgnorer returns an ignorer honouring .txt2llmignore files and, if git is
+// set, gitignore rules.
+func newIgnorer(git bool) *ignorer {
+	cwd, _ := os.Getwd()
+	return &ignorer{
+		git:       git,
+		cwd:       cwd,
+		fileRules: map[string][]rule{},
+		repoRoots: map[string]string{},
+		repoRules: map[string][]rule{},
+	}
+}
+
+// ignored reports whether path should be skipped. Only the path itself is
+// checked; callers walking a tree prune ignored directories as they go.
+func (ig *ignorer) ignored(path string, isDir bool) bool {
+	if ig == nil {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if ig.git && isDir && filepath.Base(abs) == ".git" {
+		return true
+	}
+
+	ignored := false
+	apply := func(rules []rule) {
+		for _, r := range rules {
+			if r.matches(abs, isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+
+	root := ig.repoRoot(filepath.Dir(abs))
+	git := ig.git && root != ""
+	if git {
+		apply(ig.rootRules(root))
+	}
+	chain := dirChain(root, filepath.Dir(abs))
+	if ig.cwd != "" && !slices.Contains(chain, ig.cwd) {
+		apply(ig.rulesIn(ig.cwd, txt2llmignoreFile))
+	}
+	for _, dir := range chain {
+		if git {
+			apply(ig.rulesIn(dir, gitignoreFile))
+		}
+		apply(ig.rulesIn(dir, txt2llmignoreFile))
+	}
+	if ignored && ig.report != nil {
+		if isDir {
+			abs += string(filepath.Separator)
+		}
+		ig.report(abs)
+	}
+	return ignored
+}
+
+// repoRoot returns the nearest ancestor of dir (inclusive) containing a .git
+// entry, or "" when dir is not inside a repository.
+func (ig *ignorer) repoRoot(dir string) string {
+	if root, ok := ig.repoRoots[dir]; ok {
+		return root
+	}
+	root := ""
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = ig.repoRoot(parent)
+	}
+	ig.repoRoots[dir] = root
+	return root
+}
+
+// rulesIn returns the rules from the ignore file called name in dir.
+func (ig *ignorer) rulesIn(dir, name string) []rule {
+	path := filepath.Join(dir, name)
+	if rules, ok := ig.fileRules[path]; ok {
+		return rules
+	}
+	rules := loadRules(path, dir)
+	ig.fileRules[path] = rules
+	return rules
+}
+
+// rootRules returns the repository-wide rules for root, lowest precedence first.
+func (ig *ignorer) rootRules(root string) []rule {
+	if rules, ok := ig.repoRules[root]; ok {
+		return rules
+	}
+	var rules []rule
+	if path := ig.globalExcludes(); path != "" {
+		rules = append(rules, loadRules(path, root)...)
+	}
+	rules = append(rules, loadRules(filepath.Join(root, ".git", "info", "exclude"), root)...)
+	ig.repoRules[root] = rules
+	return rules
+}
+
+// globalExcludes locates the user's global excludes file: git's
+// core.excludesFile setting if available, otherwise git's XDG default.
+func (ig *ignorer) globalExcludes() string {
+	if ig.global != nil {
+		return *ig.global
+	}
+	path := ""
+	if out, err := exec.Command("git", "config", "--path", "--get", "core.excludesFile").Output(); err == nil {
+		path = strings.TrimSpace(string(out))
+	}
+	if path == "" {
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			path = filepath.Join(xdg, "git", "ignore")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, ".config", "git", "ignore")
+		}
+	}
+	ig.global = &path
+	return path
+}
+
+// loadRules reads and compiles an ignore file, returning nil if it is missing.
+func loadRules(path, base string) []rule {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return parseRules(string(data), base)
+}
+
+// dirChain lists the directories from top down to dir, both inclusive. dir
+// must be top or one of its descendants; an empty top means the filesystem
+// root.
+func dirChain(top, dir string) []string {
+	var chain []string
+	for {
+		chain = append(chain, dir)
+		if dir == top {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	slices.Reverse(chain)
+	return chain
+}
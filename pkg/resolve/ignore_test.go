@@ -0,0 +1,264 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseRule verifies that gitignore lines compile to rules with the right negation, anchoring and directory flags.
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantOK   bool
+		expected rule
+	}{
+		{name: "blank line", line: "", wantOK: false},
+		{name: "comment", line: "# comment", wantOK: false},
+		{name: "basename pattern", line: "*.log", wantOK: true, expected: rule{pattern: "*.log"}},
+		{name: "trailing spaces trimmed", line: "*.log   ", wantOK: true, expected: rule{pattern: "*.log"}},
+		{name: "negation", line: "!keep.log", wantOK: true, expected: rule{pattern: "keep.log", negate: true}},
+		{name: "escaped bang", line: `\!important`, wantOK: true, expected: rule{pattern: "!important"}},
+		{name: "escaped hash", line: `\#file`, wantOK: true, expected: rule{pattern: "#file"}},
+		{name: "directory only", line: "build/", wantOK: true, expected: rule{pattern: "build", dirOnly: true}},
+		{name: "leading slash anchors", line: "/todo.txt", wantOK: true, expected: rule{pattern: "todo.txt", anchored: true}},
+		{name: "inner slash anchors", line: "doc/*.txt", wantOK: true, expected: rule{pattern: "doc/*.txt", anchored: true}},
+		{name: "doublestar", line: "**/logs/", wantOK: true, expected: rule{pattern: "**/logs", anchored: true, dirOnly: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok := parseRule(tt.line, "")
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.expected, r)
+			}
+		})
+	}
+}
+
+// TestFilesGitignore verifies that directory walks and globs honour nested .gitignore files, negations,
+// directory-only rules, .git/info/exclude and the global excludes file, and that the filter can be disabled.
+func TestFilesGitignore(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(configDir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+
+	testFiles := map[string]string{
+		filepath.Join(configDir, "git/ignore"): "*.swp\n",
+		".git/HEAD":                            "ref: refs/heads/main\n",
+		".git/info/exclude":                    "local.txt\n",
+		".gitignore":                           "node_modules/\n*.log\n!keep.log\nbuild\n/root-only.txt\n",
+		"main.go":                              "package main",
+		"debug.log":                            "noise",
+		"keep.log":                             "signal",
+		"local.txt":                            "mine",
+		"notes.swp":                            "swap",
+		"root-only.txt":                        "root",
+		"build/out.bin":                        "binary",
+		"node_modules/pkg/index.js":            "js",
+		"src/app.go":                           "package src",
+		"src/root-only.txt":                    "nested",
+		"src/.gitignore":                       "generated/\n!debug.log\n",
+		"src/debug.log":                        "kept by nested negation",
+		"src/generated/gen.go":                 "package generated",
+	}
+
+	for path, content := range testFiles {
+		fullPath := path
+		if !filepath.IsAbs(path) {
+			fullPath = filepath.Join(tmpDir, path)
+		}
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+
+	oldWd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmpDir))
+	defer func() { os.Chdir(oldWd) }()
+
+	tests := []struct {
+		name     string
+		patterns []string
+		opts     Options
+		expected []string
+	}{
+		{
+			name:     "recursive walk honours ignore rules",
+			patterns: []string{"."},
+			opts:     Options{Recursive: true},
+			expected: []string{".gitignore", "main.go", "keep.log", "src/app.go", "src/root-only.txt", "src/.gitignore", "src/debug.log"},
+		},
+		{
+			name:     "non-recursive directory honours ignore rules",
+			patterns: []string{"."},
+			opts:     Options{},
+			expected: []string{".gitignore", "main.go", "keep.log"},
+		},
+		{
+			name:     "globs honour ignore rules",
+			patterns: []string{"**/*.{go,log,js}"},
+			opts:     Options{},
+			expected: []string{"main.go", "keep.log", "src/app.go", "src/debug.log"},
+		},
+		{
+			name:     "explicit files bypass ignore rules",
+			patterns: []string{"debug.log"},
+			opts:     Options{},
+			expected: []string{"debug.log"},
+		},
+		{
+			name:     "explicitly named directory is walked",
+			patterns: []string{"build"},
+			opts:     Options{Recursive: true},
+			expected: []string{"build/out.bin"},
+		},
+		{
+			name:     "disabled gitignore includes everything",
+			patterns: []string{"src"},
+			opts:     Options{Recursive: true, NoGitignore: true},
+			expected: []string{"src/app.go", "src/root-only.txt", "src/.gitignore", "src/debug.log", "src/generated/gen.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Files(tt.patterns, tt.opts)
+			require.NoError(t, err)
+
+			var expected []string
+			for _, p := range tt.expected {
+				abs, _ := filepath.Abs(p)
+				expected = append(expected, abs)
+			}
+			assert.ElementsMatch(t, expected, files)
+		})
+	}
+}
+
+// TestIgnorerOutsideRepository verifies that .gitignore files are not consulted outside a git repository.
+func TestIgnorerOutsideRepository(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte("*.log\n"), 0644))
+
+	ign := newIgnorer(true)
+	assert.False(t, ign.ignored(filepath.Join(tmpDir, "debug.log"), false))
+}
+
+// TestFilesTxt2llmignore verifies that .txt2llmignore files in walked directories and the working directory are
+// honoured by walks and globs, inside and outside repositories, even when gitignore handling is disabled.
+func TestFilesTxt2llmignore(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testFiles := map[string]string{
+		".txt2llmignore":          "*.lock\n",
+		"go.lock":                 "lockfile",
+		"main.go":                 "package main",
+		"fixtures/big.json":       "{}",
+		"db/dump.sql":             "INSERT",
+		"db/schema.sql":           "CREATE",
+		"db/.txt2llmignore":       "dump.sql\n",
+		"repo/.git/HEAD":          "ref: refs/heads/main\n",
+		"repo/.txt2llmignore":     "fixtures/\n",
+		"repo/app.go":             "package app",
+		"repo/app.lock":           "lockfile",
+		"repo/fixtures/case.json": "{}",
+	}
+
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+
+	oldWd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmpDir))
+	defer func() { os.Chdir(oldWd) }()
+
+	tests := []struct {
+		name     string
+		patterns []string
+		opts     Options
+		expected []string
+	}{
+		{
+			name:     "walk honours nested and working directory files",
+			patterns: []string{"."},
+			opts:     Options{Recursive: true},
+			expected: []string{".txt2llmignore", "main.go", "fixtures/big.json", "db/schema.sql", "db/.txt2llmignore", "repo/.txt2llmignore", "repo/app.go"},
+		},
+		{
+			name:     "glob honours nested files",
+			patterns: []string{"**/*.sql"},
+			opts:     Options{},
+			expected: []string{"db/schema.sql"},
+		},
+		{
+			name:     "still applied without gitignore",
+			patterns: []string{"repo"},
+			opts:     Options{Recursive: true, NoGitignore: true},
+			expected: []string{"repo/.txt2llmignore", "repo/app.go", "repo/.git/HEAD"},
+		},
+		{
+			name:     "explicit files bypass",
+			patterns: []string{"db/dump.sql"},
+			opts:     Options{},
+			expected: []string{"db/dump.sql"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Files(tt.patterns, tt.opts)
+			require.NoError(t, err)
+
+			var expected []string
+			for _, p := range tt.expected {
+				abs, _ := filepath.Abs(p)
+				expected = append(expected, abs)
+			}
+			assert.ElementsMatch(t, expected, files)
+		})
+	}
+}
+
+// TestFilesReportsIgnored verifies that Options.Ignored receives each ignored file and pruned directory once, and
+// never the .git directory.
+func TestFilesReportsIgnored(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(tmpDir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+	for path, content := range map[string]string{
+		".git/HEAD":       "ref: refs/heads/main\n",
+		".gitignore":      "*.log\nvendor/\n",
+		"main.go":         "package main",
+		"debug.log":       "noise",
+		"vendor/lib/x.go": "package lib",
+	} {
+		full := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+
+	var ignored []string
+	files, err := Files([]string{tmpDir, filepath.Join(tmpDir, "*")}, Options{
+		Recursive: true,
+		Ignored:   func(path string) { ignored = append(ignored, path) },
+	})
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(tmpDir, "debug.log"),
+		filepath.Join(tmpDir, "vendor") + string(filepath.Separator),
+	}, ignored)
+}
@@ -7,11 +7,43 @@ import (
 	"path/filepath"
 )
 
+// Options controls how patterns are resolved to files.
+type Options struct {
+	// Recursive walks directories instead of listing only their direct entries.
+	Recursive bool
+	// NoGitignore disables filtering of walked and globbed paths by .gitignore
+	// files, .git/info/exclude and the global excludes file. .txt2llmignore
+	// files are honoured regardless.
+	NoGitignore bool
+	// Include, when non-empty, keeps only files matching at least one pattern.
+	Include []string
+	// Exclude drops files matching any pattern. Exclude takes precedence over
+	// Include.
+	Exclude []string
+	// Ignored, if set, is called once with the absolute path of each file or
+	// directory skipped by ignore rules. Directories end in a separator; their
+	// contents are not visited.
+	Ignored func(path string)
+	// Git, when enabled, selects files from git instead of walking
+	// directories, and patterns only narrow that selection.
+	Git Git
+	// Rev, if set, selects the files in this git revision instead, which
+	// need not exist in the working tree; read them with a RevisionReader.
+	// Patterns narrow the selection as with Git.
+	Rev string
+}
+
 // Files resolves patterns (files, directories, globs) to a deduplicated slice of
-// absolute file paths. Returns an error if no files match.
-func Files(patterns []string, recursive bool) ([]string, error) {
+// absolute file paths. Files named explicitly are always included; ignore rules
+// only filter paths found by walking directories or expanding globs, while
+// include and exclude patterns apply to every file. With opts.Git enabled or
+// opts.Rev set, files come from git and patterns select among them; with no
+// patterns, every file git selects under the working directory is used.
+// Returns an error if no files match.
+func Files(patterns []string, opts Options) ([]string, error) {
 	seen := map[string]struct{}{}
 	out := []string{}
+	flt := newFilter(opts.Include, opts.Exclude)
 	add := func(path string) {
 		if path == "" {
 			return
@@ -20,12 +52,41 @@ func Files(patterns []string, recursive bool) ([]string, error) {
 		if err != nil {
 			abs = path
 		}
+		if !flt.keep(abs) {
+			return
+		}
 		if _, ok := seen[abs]; !ok {
 			seen[abs] = struct{}{}
 			out = append(out, abs)
 		}
 	}
 
+	fromGit := opts.Git.Enabled() || opts.Rev != ""
+	ign := newIgnorer(!opts.NoGitignore && !fromGit)
+	if opts.Ignored != nil {
+		reported := map[string]struct{}{}
+		ign.report = func(path string) {
+			if _, ok := reported[path]; !ok {
+				reported[path] = struct{}{}
+				opts.Ignored(path)
+			}
+		}
+	}
+
+	if fromGit {
+		what, err := gitFiles(opts.Git, opts.Rev, patterns, opts.Recursive, ign, add)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) == 0 {
+			if len(patterns) == 0 {
+				return nil, fmt.Errorf("no %s found", what)
+			}
+			return nil, fmt.Errorf("no %s matched any of the patterns: %v", what, patterns)
+		}
+		return out, nil
+	}
+
 	for _, pat := range patterns {
 		if pat == "" {
 			continue
@@ -36,11 +97,11 @@ func Files(patterns []string, recursive bool) ([]string, error) {
 				continue
 			}
 			if info.IsDir() {
-				addDir(pat, recursive, add)
+				addDir(pat, opts.Recursive, ign, add)
 				continue
 			}
 		}
-		addGlob(pat, add)
+		addGlob(pat, ign, add)
 	}
 	if len(out) == 0 {
 		return nil, fmt.Errorf("no files matched any of the patterns: %v", patterns)
@@ -48,15 +109,21 @@ func Files(patterns []string, recursive bool) ([]string, error) {
 	return out, nil
 }
 
-// addDir adds regular files from the specified directory.
-// If recursive is true, it walks the directory tree.
-func addDir(dir string, recursive bool, add func(string)) {
+// addDir adds regular files from the specified directory, skipping anything
+// ign ignores. If recursive is true, it walks the directory tree.
+func addDir(dir string, recursive bool, ign *ignorer, add func(string)) {
 	if recursive {
 		_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
 			if err != nil {
 				return nil
 			}
-			if d.Type().IsRegular() {
+			if d.IsDir() {
+				if path != dir && ign.ignored(path, true) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Type().IsRegular() && !ign.ignored(path, false) {
 				add(path)
 			}
 			return nil
@@ -65,16 +132,17 @@ func addDir(dir string, recursive bool, add func(string)) {
 	}
 	entries, _ := os.ReadDir(dir)
 	for _, e := range entries {
-		if e.Type().IsRegular() {
-			add(filepath.Join(dir, e.Name()))
+		path := filepath.Join(dir, e.Name())
+		if e.Type().IsRegular() && !ign.ignored(path, false) {
+			add(path)
 		}
 	}
 }
 
-// addGlob adds regular files matching the glob pattern.
-func addGlob(pat string, add func(string)) {
-	matches, _ := filepath.Glob(pat)
-	for _, m := range matches {
+// addGlob adds regular files matching the glob pattern, skipping anything ign
+// ignores.
+func addGlob(pat string, ign *ignorer, add func(string)) {
+	for _, m := range glob(pat, ign) {
 		if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
 			add(m)
 		}
@@ -126,7 +126,7 @@ func TestFiles(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			files, err := Files(tt.patterns, tt.recursive)
+			files, err := Files(tt.patterns, Options{Recursive: tt.recursive})
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -229,7 +229,7 @@ func TestAddDir(t *testing.T) {
 				}
 			}
 
-			addDir(tt.dir, tt.recursive, add)
+			addDir(tt.dir, tt.recursive, nil, add)
 
 			assert.ElementsMatch(t, tt.expected, collected)
 		})
@@ -299,7 +299,7 @@ func TestAddGlob(t *testing.T) {
 				}
 			}
 
-			addGlob(tt.pattern, add)
+			addGlob(tt.pattern, nil, add)
 
 			assert.ElementsMatch(t, tt.expected, collected)
 		})
@@ -321,14 +321,14 @@ func TestFilesEdgeCases(t *testing.T) {
 	defer func() { os.Chdir(oldWd) }()
 
 	t.Run("empty directory non-recursive", func(t *testing.T) {
-		files, err := Files([]string{"empty"}, false)
+		files, err := Files([]string{"empty"}, Options{})
 		assert.Error(t, err) // Should error because no files found
 		assert.Nil(t, files)
 		assert.Contains(t, err.Error(), "no files matched")
 	})
 
 	t.Run("empty directory recursive", func(t *testing.T) {
-		files, err := Files([]string{"empty"}, true)
+		files, err := Files([]string{"empty"}, Options{Recursive: true})
 		assert.Error(t, err) // Should error because no files found
 		assert.Nil(t, files)
 		assert.Contains(t, err.Error(), "no files matched")
@@ -346,7 +346,7 @@ func TestAddDirErrorHandling(t *testing.T) {
 		}
 
 		// This should not panic and should gracefully handle the error
-		addDir("/nonexistent/directory/path", false, add)
+		addDir("/nonexistent/directory/path", false, nil, add)
 
 		// Should collect nothing
 		assert.Empty(t, collected)
@@ -0,0 +1,198 @@
+package tokens
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BPE counts tokens exactly using a byte-pair encoding vocabulary such as
+// cl100k_base or o200k_base.
+type BPE struct {
+	ranks map[string]int
+}
+
+// LoadBPE reads a vocabulary in tiktoken format: one token per line, as
+// base64 followed by its merge rank.
+func LoadBPE(path string) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ranks := make(map[string]int)
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		fields := bytes.Fields(sc.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want \"<base64 token> <rank>\"", path, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("%s: empty vocabulary", path)
+	}
+	return NewBPE(ranks), nil
+}
+
+// NewBPE returns a BPE counter for a vocabulary mapping tokens to merge ranks.
+func NewBPE(ranks map[string]int) *BPE {
+	return &BPE{ranks: ranks}
+}
+
+// Count returns the number of tokens text encodes to.
+func (b *BPE) Count(text []byte) int {
+	n := 0
+	for len(text) > 0 {
+		size := nextPiece(text)
+		n += b.countPiece(text[:size])
+		text = text[size:]
+	}
+	return n
+}
+
+// countPiece merges the bytes of piece pairwise, lowest rank first, until no
+// adjacent pair is in the vocabulary, and returns the number of parts left.
+func (b *BPE) countPiece(piece []byte) int {
+	if _, ok := b.ranks[string(piece)]; ok {
+		return 1
+	}
+	// bounds[i] is the start of part i; the last entry is len(piece).
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	rank := func(i int) int {
+		if i+2 >= len(bounds) {
+			return math.MaxInt
+		}
+		if r, ok := b.ranks[string(piece[bounds[i]:bounds[i+2]])]; ok {
+			return r
+		}
+		return math.MaxInt
+	}
+	ranks := make([]int, len(bounds)-1)
+	for i := range ranks {
+		ranks[i] = rank(i)
+	}
+	for len(bounds) > 2 {
+		best := 0
+		for i, r := range ranks {
+			if r < ranks[best] {
+				best = i
+			}
+		}
+		if ranks[best] == math.MaxInt {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+		ranks = append(ranks[:best], ranks[best+1:]...)
+		ranks[best] = rank(best)
+		if best > 0 {
+			ranks[best-1] = rank(best - 1)
+		}
+	}
+	return len(bounds) - 1
+}
+
+// nextPiece returns the byte length of the first pre-tokenization piece of
+// text, following the cl100k split pattern:
+//
+//	'(?i:s|t|re|ve|m|ll|d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func nextPiece(text []byte) int {
+	r, size := utf8.DecodeRune(text)
+
+	if r == '\'' {
+		if n := contraction(text[size:]); n > 0 {
+			return size + n
+		}
+	}
+
+	if unicode.IsLetter(r) {
+		return size + span(text[size:], unicode.IsLetter, -1)
+	}
+	if r != '\r' && r != '\n' && !unicode.IsNumber(r) {
+		if n := span(text[size:], unicode.IsLetter, -1); n > 0 {
+			return size + n
+		}
+	}
+
+	if unicode.IsNumber(r) {
+		return size + span(text[size:], unicode.IsNumber, 2)
+	}
+
+	start := 0
+	if r == ' ' {
+		start = size
+	}
+	if n := span(text[start:], isSymbol, -1); n > 0 {
+		end := start + n
+		return end + span(text[end:], isNewline, -1)
+	}
+
+	ws := span(text, unicode.IsSpace, -1)
+	if ws == 0 {
+		return size
+	}
+	if i := bytes.LastIndexAny(text[:ws], "\r\n"); i >= 0 {
+		return i + 1
+	}
+	if ws < len(text) {
+		// Leave the last space to prefix the following word.
+		if _, last := utf8.DecodeLastRune(text[:ws]); ws > last {
+			return ws - last
+		}
+	}
+	return ws
+}
+
+// contraction returns the length of an English contraction suffix such as
+// "s" or "ll" at the start of text, or 0.
+func contraction(text []byte) int {
+	for _, suffix := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+		if len(text) >= len(suffix) && bytes.EqualFold(text[:len(suffix)], []byte(suffix)) {
+			return len(suffix)
+		}
+	}
+	return 0
+}
+
+// span returns the byte length of the run of runes at the start of text that
+// satisfy in, stopping after limit runes if limit >= 0.
+func span(text []byte, in func(rune) bool, limit int) int {
+	size := 0
+	for count := 0; size < len(text) && count != limit; count++ {
+		r, n := utf8.DecodeRune(text[size:])
+		if !in(r) {
+			break
+		}
+		size += n
+	}
+	return size
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
@@ -0,0 +1,79 @@
+package tokens
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNextPiece verifies pre-tokenization splits text the way the cl100k pattern does.
+func TestNextPiece(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{text: "Hello world", expected: []string{"Hello", " world"}},
+		{text: "don't", expected: []string{"don", "'t"}},
+		{text: "12345", expected: []string{"123", "45"}},
+		{text: "f(x);\n", expected: []string{"f", "(x", ");\n"}},
+		{text: "a  b", expected: []string{"a", " ", " b"}},
+		{text: "x\n\n  y", expected: []string{"x", "\n\n", " ", " y"}},
+		{text: "end   ", expected: []string{"end", "   "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var pieces []string
+			for text := []byte(tt.text); len(text) > 0; {
+				n := nextPiece(text)
+				pieces = append(pieces, string(text[:n]))
+				text = text[n:]
+			}
+			assert.Equal(t, tt.expected, pieces)
+		})
+	}
+}
+
+// TestBPECount verifies that pieces merge lowest rank first and unknown bytes count as single tokens.
+func TestBPECount(t *testing.T) {
+	bpe := NewBPE(map[string]int{
+		"h": 0, "e": 1, "l": 2, "o": 3, " ": 4, "w": 5, "r": 6, "d": 7,
+		"ll": 8, "he": 9, "llo": 10, "hello": 11, " w": 12, "or": 13,
+	})
+
+	assert.Equal(t, 1, bpe.Count([]byte("hello")))
+	// " world" merges to " w", "or", "l", "d".
+	assert.Equal(t, 5, bpe.Count([]byte("hello world")))
+	assert.Equal(t, 3, bpe.Count([]byte("xyz")))
+	assert.Equal(t, 0, bpe.Count(nil))
+}
+
+// TestLoadBPE verifies that tiktoken-format vocabularies load and malformed files are rejected.
+func TestLoadBPE(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var lines []string
+	for i, tok := range []string{"a", "b", "ab"} {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte(tok)), i))
+	}
+	good := filepath.Join(tmpDir, "good.tiktoken")
+	require.NoError(t, os.WriteFile(good, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+
+	bpe, err := LoadBPE(good)
+	require.NoError(t, err)
+	assert.Equal(t, 2, bpe.Count([]byte("abab")))
+
+	bad := filepath.Join(tmpDir, "bad.tiktoken")
+	require.NoError(t, os.WriteFile(bad, []byte("YQ== 0\nnot-base64! 1\n"), 0644))
+	_, err = LoadBPE(bad)
+	assert.ErrorContains(t, err, "bad.tiktoken:2")
+
+	_, err = LoadBPE(filepath.Join(tmpDir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+// Package tokens counts LLM tokens, either exactly with a byte-pair encoding
+// vocabulary or approximately with a fast heuristic.
+package tokens
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Counter counts the tokens in a piece of text.
+type Counter interface {
+	Count(text []byte) int
+}
+
+// Heuristic estimates token counts without a vocabulary. It follows the
+// shape of cl100k/o200k tokenization: short words are one token, long words,
+// digit runs and symbol runs are split, and CJK characters count one each. It
+// is usually within 10-15% of the real count for code and English prose.
+type Heuristic struct{}
+
+// Count estimates the number of tokens in text.
+func (Heuristic) Count(text []byte) int {
+	n := 0
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		switch {
+		case isCJK(r):
+			n++
+		case unicode.IsLetter(r):
+			size = runLen(text, unicode.IsLetter)
+			n += 1 + (utf8.RuneCount(text[:size])-1)/6
+		case unicode.IsNumber(r):
+			size = runLen(text, unicode.IsNumber)
+			n += (utf8.RuneCount(text[:size]) + 2) / 3
+		case r == '\n' || r == '\r':
+			size = runLen(text, func(r rune) bool { return r == '\n' || r == '\r' })
+			n++
+		case unicode.IsSpace(r):
+			size = runLen(text, func(r rune) bool { return unicode.IsSpace(r) && r != '\n' && r != '\r' })
+			// A single space is folded into the following word.
+			if size > 1 {
+				n += 1 + (size-1)/8
+			}
+		default:
+			size = runLen(text, isSymbol)
+			runes := utf8.RuneCount(text[:size])
+			// A symbol directly before a word is folded into it.
+			if next, _ := utf8.DecodeRune(text[size:]); size < len(text) && unicode.IsLetter(next) {
+				runes--
+			}
+			n += (runes + 1) / 2
+		}
+		text = text[size:]
+	}
+	return n
+}
+
+// runLen returns the byte length of the run of runes at the start of text
+// that satisfy in.
+func runLen(text []byte, in func(rune) bool) int {
+	size := 0
+	for size < len(text) {
+		r, n := utf8.DecodeRune(text[size:])
+		if !in(r) || (size > 0 && isCJK(r)) {
+			break
+		}
+		size += n
+	}
+	return max(size, 1)
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsSpace(r)
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,41 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHeuristic verifies the estimator's counts for words, numbers, whitespace, symbols and CJK text.
+func TestHeuristic(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{text: "", expected: 0},
+		{text: "hello", expected: 1},
+		{text: "hello world", expected: 2},
+		{text: "internationalization", expected: 4},
+		{text: "1234567", expected: 3},
+		{text: "a\n\nb", expected: 3},
+		{text: "        x", expected: 2},
+		{text: "f(x);", expected: 3},
+		{text: "日本語", expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Heuristic{}.Count([]byte(tt.text)))
+		})
+	}
+}
+
+// TestHeuristicScale verifies the estimate for typical source code is in the usual range of three to four bytes per
+// token.
+func TestHeuristicScale(t *testing.T) {
+	src := strings.Repeat("func (s *Server) Handle(w http.ResponseWriter, r *http.Request) {\n\tif err := s.check(r); err != nil {\n\t\treturn\n\t}\n}\n", 20)
+	n := Heuristic{}.Count([]byte(src))
+	assert.GreaterOrEqual(t, n, len(src)/4)
+	assert.LessOrEqual(t, n, len(src)/3)
+}
@@ -0,0 +1,338 @@
+// Package txt2llm bundles files into a single LLM-ready document. It is the
+// library behind the txt2llm command: everything the CLI can do is available
+// through Options, and nothing is printed unless Options.Warnings is set.
+package txt2llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/matthewchivers/txt2llm/pkg/diff"
+	"github.com/matthewchivers/txt2llm/pkg/output"
+	"github.com/matthewchivers/txt2llm/pkg/redact"
+	"github.com/matthewchivers/txt2llm/pkg/resolve"
+	"github.com/matthewchivers/txt2llm/pkg/tokens"
+)
+
+// Options describes which files to bundle and how to render them.
+type Options struct {
+	// Patterns are files, directories or globs to bundle.
+	Patterns []string
+	// Recursive walks directories recursively.
+	Recursive bool
+	// Relative shows paths relative to the working directory.
+	Relative bool
+	// NoGitignore includes files ignored by .gitignore.
+	NoGitignore bool
+	// Include and Exclude filter the resolved files by glob; Exclude wins.
+	Include []string
+	Exclude []string
+	// Git, when enabled, takes the files from git; Patterns then narrow them.
+	Git resolve.Git
+	// Rev, if set, takes paths and contents from this git revision instead
+	// of the working tree; Patterns then narrow them.
+	Rev string
+	// DiffBase, if set, emits each file as a unified diff against its
+	// version in this git revision or directory, skipping unchanged files.
+	DiffBase string
+	// DiffContext is the number of unchanged lines around each change.
+	DiffContext int
+	// DiffFullUnder also emits the whole of each changed file smaller than
+	// this many bytes.
+	DiffFullUnder int
+
+	// Format selects the output structure.
+	Format output.Format
+	// MarkerPrefix and MarkerSuffix surround START/END marker lines.
+	MarkerPrefix string
+	MarkerSuffix string
+	// OnCollision selects what happens when a file contains the markers.
+	OnCollision output.CollisionMode
+	// Binary selects how binary files are handled.
+	Binary output.BinaryMode
+	// Secrets selects whether secrets are redacted or fail the bundle, and
+	// whether sensitive files such as .env are skipped.
+	Secrets output.SecretMode
+	// Redactor finds secrets and sensitive files; nil uses redact.New().
+	Redactor *redact.Redactor
+
+	// Tokenizer counts tokens for Stats and MaxTokens; nil uses
+	// tokens.Heuristic.
+	Tokenizer tokens.Counter
+	// MaxTokens caps the tokens in the whole bundle; 0 means no limit.
+	MaxTokens int
+	// OnBudget selects what happens once MaxTokens would be exceeded.
+	OnBudget output.BudgetMode
+
+	// Tree adds a tree of the included files before the first file.
+	Tree bool
+	// TreeSizes annotates each tree node with its size and tokens.
+	TreeSizes bool
+
+	// Jobs is the number of files read at once; 0 or 1 reads them one at a
+	// time. The bundle is the same either way.
+	Jobs int
+	// MaxBuffered caps the bytes read ahead of the file being written; 0
+	// means no limit.
+	MaxBuffered int64
+	// StreamOver copies files larger than this many bytes in chunks rather
+	// than reading them whole, in the marker format without a token budget
+	// or diff; 0 reads every file whole.
+	StreamOver int64
+
+	// OutputPath, if set, names the file the bundle is being written to so
+	// that it is never bundled into itself. Bundle does not create it.
+	OutputPath string
+	// Warnings receives notes about skipped files, and patterns that look
+	// meant as the base of Git.Changed. Nil discards them.
+	Warnings io.Writer
+}
+
+// Stats summarises a bundle.
+type Stats = output.Stats
+
+// FileStats describes what happened to one input file.
+type FileStats = output.FileStats
+
+// DefaultJobs and DefaultMaxBuffered are the CLI's defaults for Jobs and
+// MaxBuffered: enough parallelism to hide network filesystem latency without
+// holding more than 256 MiB of read-ahead. DefaultStreamOver is its default
+// for StreamOver.
+const (
+	DefaultJobs        = 8
+	DefaultMaxBuffered = 256 << 20
+	DefaultStreamOver  = 64 << 20
+)
+
+// DefaultOptions returns the options the CLI uses when no flags are given.
+func DefaultOptions() Options {
+	return Options{
+		MarkerPrefix: "<<<",
+		MarkerSuffix: ">>>",
+		DiffContext:  diff.DefaultContext,
+		Jobs:         DefaultJobs,
+		MaxBuffered:  DefaultMaxBuffered,
+		StreamOver:   DefaultStreamOver,
+	}
+}
+
+// Bundle resolves opts.Patterns and writes the matching files to w. The
+// returned Stats also lists files and directories skipped by ignore rules.
+func Bundle(ctx context.Context, opts Options, w io.Writer) (Stats, error) {
+	files, ignored, err := resolveFiles(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+	ro, done, err := renderOptions(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer done()
+	stats, err := output.Render(ctx, w, files, output.Paths(files, opts.Relative), ro)
+	stats.Files = append(stats.Files, ignoredStats(ignored, opts.Relative)...)
+	return stats, err
+}
+
+// List resolves opts.Patterns like Bundle without writing anything. If
+// measure is set, each file is also read and its size, lines and content
+// tokens reported, with binary files and secrets handled as Bundle would;
+// otherwise only Source and Path are set, and sensitive files are marked
+// skipped by name. Files skipped by ignore rules are listed at the end.
+func List(ctx context.Context, opts Options, measure bool) (Stats, error) {
+	files, ignored, err := resolveFiles(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+	outPaths := output.Paths(files, opts.Relative)
+	var stats Stats
+	if measure {
+		ro, done, err := renderOptions(opts)
+		if err != nil {
+			return stats, err
+		}
+		defer done()
+		if stats, err = output.Measure(ctx, files, outPaths, ro); err != nil {
+			return stats, err
+		}
+	} else {
+		r := opts.Redactor
+		if r == nil {
+			r = redact.New()
+		}
+		for i, f := range files {
+			st := FileStats{Source: f, Path: outPaths[i]}
+			if opts.Secrets != output.SecretOff && r.Sensitive(f) {
+				st.Skipped = "sensitive"
+			}
+			stats.Files = append(stats.Files, st)
+		}
+	}
+	stats.Files = append(stats.Files, ignoredStats(ignored, opts.Relative)...)
+	return stats, nil
+}
+
+// resolveFiles returns the files opts selects, and the paths ignore rules
+// skipped.
+func resolveFiles(opts Options) (files, ignored []string, err error) {
+	if opts.Warnings != nil {
+		for _, rev := range opts.Git.RevisionPatterns(opts.Patterns) {
+			fmt.Fprintf(opts.Warnings, "Reading %s as a pattern, not as the base of --git-changed; "+
+				"use --git-changed=%s to compare with it\n", rev, rev)
+		}
+	}
+	files, err = resolve.Files(opts.Patterns, resolve.Options{
+		Recursive:   opts.Recursive,
+		NoGitignore: opts.NoGitignore,
+		Include:     opts.Include,
+		Exclude:     opts.Exclude,
+		Git:         opts.Git,
+		Rev:         opts.Rev,
+		Ignored:     func(path string) { ignored = append(ignored, path) },
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.OutputPath != "" {
+		files = withoutFile(files, opts.OutputPath, opts.Warnings)
+		if len(files) == 0 {
+			return nil, nil, fmt.Errorf("no files matched any of the patterns besides the output file %s", opts.OutputPath)
+		}
+	}
+	return files, ignored, nil
+}
+
+// renderOptions returns the output options opts selects, reading files from
+// opts.Rev and diffing them against opts.DiffBase if they are set. Call done
+// once rendering is over to stop reading from git.
+func renderOptions(opts Options) (ro output.Options, done func(), err error) {
+	ro = output.Options{
+		Format:        opts.Format,
+		MarkerPrefix:  opts.MarkerPrefix,
+		MarkerSuffix:  opts.MarkerSuffix,
+		Binary:        opts.Binary,
+		Secrets:       opts.Secrets,
+		Redactor:      opts.Redactor,
+		OnCollision:   opts.OnCollision,
+		Warnings:      opts.Warnings,
+		Tokenizer:     opts.Tokenizer,
+		MaxTokens:     opts.MaxTokens,
+		OnBudget:      opts.OnBudget,
+		Tree:          opts.Tree,
+		TreeSizes:     opts.TreeSizes,
+		Jobs:          opts.Jobs,
+		MaxBuffered:   opts.MaxBuffered,
+		StreamOver:    opts.StreamOver,
+		DiffContext:   opts.DiffContext,
+		DiffFullUnder: opts.DiffFullUnder,
+	}
+	var readers []*resolve.RevisionReader
+	closeAll := func() {
+		for _, r := range readers {
+			_ = r.Close()
+		}
+	}
+	defer func() {
+		if err != nil {
+			closeAll()
+		}
+	}()
+	if opts.Rev != "" {
+		name, err := resolve.DescribeRevision(opts.Rev)
+		if err != nil {
+			return output.Options{}, nil, err
+		}
+		r, err := resolve.OpenRevision(opts.Rev)
+		if err != nil {
+			return output.Options{}, nil, err
+		}
+		readers = append(readers, r)
+		ro.Revision = name
+		ro.ReadFile = r.ReadFile
+	}
+	if opts.DiffBase != "" {
+		base, r, err := diffBase(opts.DiffBase)
+		if err != nil {
+			return output.Options{}, nil, err
+		}
+		if r != nil {
+			readers = append(readers, r)
+		}
+		ro.DiffBase = base
+	}
+	return ro, closeAll, nil
+}
+
+// diffBase returns a function reading the base version of a file from base,
+// which is a directory mirroring the working directory or a git revision,
+// and the reader to close in the latter case.
+func diffBase(base string) (func(string) ([]byte, bool, error), *resolve.RevisionReader, error) {
+	exists := func(data []byte, err error) ([]byte, bool, error) {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		return data, err == nil, err
+	}
+	if info, err := os.Stat(base); err == nil && info.IsDir() {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(path string) ([]byte, bool, error) {
+			rel, err := filepath.Rel(cwd, path)
+			if err != nil {
+				return nil, false, err
+			}
+			return exists(os.ReadFile(filepath.Join(base, rel)))
+		}, nil, nil
+	}
+	if _, err := resolve.DescribeRevision(base); err != nil {
+		return nil, nil, fmt.Errorf("diff base %s is neither a directory nor a git revision: %w", base, err)
+	}
+	r, err := resolve.OpenRevision(base)
+	if err != nil {
+		return nil, nil, err
+	}
+	return func(path string) ([]byte, bool, error) {
+		return exists(r.ReadFile(path))
+	}, r, nil
+}
+
+// ignoredStats describes paths skipped by ignore rules.
+func ignoredStats(ignored []string, relative bool) []FileStats {
+	var out []FileStats
+	for i, path := range output.Paths(ignored, relative) {
+		if strings.HasSuffix(ignored[i], string(filepath.Separator)) {
+			path += string(filepath.Separator)
+		}
+		out = append(out, FileStats{Source: ignored[i], Path: path, Skipped: "ignored"})
+	}
+	return out
+}
+
+// withoutFile drops path from files, noting it on warnings if it was present,
+// along with the temporary files output.CreateAtomic writes it through.
+func withoutFile(files []string, path string, warnings io.Writer) []string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return files
+	}
+	kept := files[:0:0]
+	for _, f := range files {
+		if output.IsTemp(f, abs) {
+			continue
+		}
+		if f == abs {
+			if warnings != nil {
+				fmt.Fprintf(warnings, "Skipping output file %s\n", f)
+			}
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
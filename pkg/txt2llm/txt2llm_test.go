@@ -0,0 +1,161 @@
+package txt2llm
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matthewchivers/txt2llm/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBundle verifies that Bundle resolves patterns, renders them in the requested format and reports stats.
+func TestBundle(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("alpha\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "b.go"), []byte("package b\n"), 0644))
+
+	t.Run("markers", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.Patterns = []string{filepath.Join(tmpDir, "a.txt")}
+
+		var buf bytes.Buffer
+		stats, err := Bundle(context.Background(), opts, &buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "<<<START:"+filepath.Join(tmpDir, "a.txt")+">>>\nalpha\n")
+		assert.Equal(t, 1, stats.Emitted())
+		assert.Equal(t, int64(buf.Len()), stats.Written)
+	})
+
+	t.Run("filtered markdown", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.Patterns = []string{tmpDir}
+		opts.Include = []string{"*.go"}
+		opts.Format = output.FormatMarkdown
+
+		var buf bytes.Buffer
+		stats, err := Bundle(context.Background(), opts, &buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "```go\npackage b\n```")
+		assert.NotContains(t, buf.String(), "alpha")
+		require.Len(t, stats.Files, 1)
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.Patterns = []string{filepath.Join(tmpDir, "*.rs")}
+
+		var buf bytes.Buffer
+		_, err := Bundle(context.Background(), opts, &buf)
+		assert.Error(t, err)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("output path is excluded", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.Patterns = []string{tmpDir}
+		opts.OutputPath = filepath.Join(tmpDir, "a.txt")
+		var buf, warnings bytes.Buffer
+		opts.Warnings = &warnings
+
+		stats, err := Bundle(context.Background(), opts, &buf)
+		require.NoError(t, err)
+		assert.NotContains(t, buf.String(), "alpha")
+		assert.Contains(t, warnings.String(), "Skipping output file")
+		assert.Equal(t, 1, stats.Emitted())
+	})
+}
+
+// TestBundleReportsIgnored verifies that files skipped by ignore rules appear in Stats with the reason "ignored".
+func TestBundleReportsIgnored(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".txt2llmignore"), []byte("*.log\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("alpha\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "debug.log"), []byte("noise\n"), 0644))
+
+	opts := DefaultOptions()
+	opts.Patterns = []string{tmpDir}
+
+	var buf bytes.Buffer
+	stats, err := Bundle(context.Background(), opts, &buf)
+	require.NoError(t, err)
+	assert.Contains(t, stats.Files, FileStats{
+		Source:  filepath.Join(tmpDir, "debug.log"),
+		Path:    filepath.Join(tmpDir, "debug.log"),
+		Skipped: "ignored",
+	})
+}
+
+// TestList verifies that List resolves the same files as Bundle without writing them, measuring them only on request
+// and counting the same tokens.
+func TestList(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("alpha\nbeta\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "b.bin"), []byte("\x00\x01"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "c.txt"), []byte("password = \"hunter22\"\n"), 0644))
+
+	var warnings bytes.Buffer
+	opts := DefaultOptions()
+	opts.Patterns = []string{tmpDir}
+	opts.Warnings = &warnings
+
+	stats, err := List(context.Background(), opts, false)
+	require.NoError(t, err)
+	assert.Equal(t, []FileStats{
+		{Source: filepath.Join(tmpDir, "a.txt"), Path: filepath.Join(tmpDir, "a.txt")},
+		{Source: filepath.Join(tmpDir, "b.bin"), Path: filepath.Join(tmpDir, "b.bin")},
+		{Source: filepath.Join(tmpDir, "c.txt"), Path: filepath.Join(tmpDir, "c.txt")},
+	}, stats.Files)
+
+	stats, err = List(context.Background(), opts, true)
+	require.NoError(t, err)
+	require.Len(t, stats.Files, 3)
+	assert.Equal(t, 11, stats.Files[0].Bytes)
+	assert.Equal(t, 2, stats.Files[0].Lines)
+	assert.Positive(t, stats.Files[0].Tokens)
+	assert.Equal(t, "binary", stats.Files[1].Skipped)
+	assert.NotContains(t, warnings.String(), "Redacted", "a listing writes nothing, so redactions are not noted")
+	assert.Equal(t, []string{"password"}, stats.Files[2].Redacted)
+
+	bundled, err := Bundle(context.Background(), opts, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, stats.Files[0].Tokens, bundled.Files[0].Tokens, "listing and bundling count the same tokens")
+
+	opts.Patterns = []string{filepath.Join(tmpDir, "*.rs")}
+	_, err = List(context.Background(), opts, false)
+	assert.Error(t, err)
+}
+
+// TestBundleDiff verifies that DiffBase diffs files against a directory mirroring the working directory, and that a
+// base which is neither a directory nor a revision is rejected.
+func TestBundleDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	work, base := filepath.Join(tmpDir, "work"), filepath.Join(tmpDir, "base")
+	require.NoError(t, os.MkdirAll(filepath.Join(work, "sub"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(base, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(work, "sub", "a.txt"), []byte("alpha\nbeta\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(base, "sub", "a.txt"), []byte("alpha\n"), 0644))
+
+	oldWd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(work))
+	defer func() { os.Chdir(oldWd) }()
+
+	opts := DefaultOptions()
+	opts.Patterns = []string{"sub"}
+	opts.Relative = true
+	opts.DiffBase = "../base"
+
+	var buf bytes.Buffer
+	_, err = Bundle(context.Background(), opts, &buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "<<<START:sub/a.txt.diff>>>\n--- a/sub/a.txt\n+++ b/sub/a.txt\n@@ -1 +1,2 @@\n alpha\n+beta\n")
+
+	opts.DiffBase = "no-such-base"
+	_, err = Bundle(context.Background(), opts, &buf)
+	assert.ErrorContains(t, err, "diff base no-such-base is neither a directory nor a git revision")
+}
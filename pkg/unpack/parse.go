@@ -0,0 +1,276 @@
+// Package unpack turns a bundle, or an LLM reply in the same format, back
+// into files.
+package unpack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/matthewchivers/txt2llm/pkg/diff"
+	"github.com/matthewchivers/txt2llm/pkg/output"
+)
+
+// File is one file recovered from a bundle.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// Options controls how a bundle is parsed.
+type Options struct {
+	// Format is the bundle's format. If Detect is set it is ignored and the
+	// format is inferred from the text.
+	Format output.Format
+	Detect bool
+	// MarkerPrefix and MarkerSuffix are the markers of a markers-format
+	// bundle. A nonce added to the suffix to avoid collisions is accepted.
+	MarkerPrefix string
+	MarkerSuffix string
+}
+
+// Parse extracts the files from data. Text outside the file sections, such as
+// a header or a model's commentary, is ignored. It fails if no files are
+// found, a section is malformed or two sections have the same path.
+func Parse(data []byte, opts Options) ([]File, error) {
+	format := opts.Format
+	if opts.Detect {
+		format = detect(data, opts)
+	}
+
+	var files []File
+	var err error
+	switch format {
+	case output.FormatXML:
+		files, err = parseXML(data)
+	case output.FormatMarkdown:
+		files, err = parseMarkdown(data)
+	case output.FormatJSON:
+		files, err = parseJSON(data)
+	case output.FormatJSONL:
+		files, err = parseJSONL(data)
+	default:
+		files, err = parseMarkers(data, opts.MarkerPrefix, opts.MarkerSuffix)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file sections found")
+	}
+	seen := map[string]bool{}
+	for _, f := range files {
+		if seen[f.Path] {
+			return nil, fmt.Errorf("more than one section for %s", f.Path)
+		}
+		seen[f.Path] = true
+	}
+	return files, nil
+}
+
+// detect infers the format of data, falling back to markers.
+func detect(data []byte, opts Options) output.Format {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		if json.Valid(trimmed) {
+			return output.FormatJSON
+		}
+		return output.FormatJSONL
+	case bytes.Contains(data, []byte("<documents>")):
+		return output.FormatXML
+	}
+	start, _ := markerPatterns(opts.MarkerPrefix, opts.MarkerSuffix)
+	if start.Match(data) {
+		return output.FormatMarkers
+	}
+	if markdownHeading.Match(data) {
+		return output.FormatMarkdown
+	}
+	return output.FormatMarkers
+}
+
+// markerPatterns returns patterns matching START and END lines for the given
+// markers, capturing the path and any nonce.
+func markerPatterns(prefix, suffix string) (start, end *regexp.Regexp) {
+	line := func(kind string) *regexp.Regexp {
+		return regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(prefix) + kind + `:(.*?)(@[0-9a-f]{8})?` +
+			regexp.QuoteMeta(suffix) + `[ \t\r]*$`)
+	}
+	return line("START"), line("END")
+}
+
+// parseMarkers extracts START/END delimited sections.
+func parseMarkers(data []byte, prefix, suffix string) ([]File, error) {
+	start, end := markerPatterns(prefix, suffix)
+	var files []File
+	var open *File
+	var nonce string
+	for _, line := range diff.SplitLines(string(data)) {
+		bare := strings.TrimRight(line, "\n")
+		if open == nil {
+			if m := start.FindStringSubmatch(bare); m != nil {
+				open = &File{Path: strings.TrimSpace(m[1]), Content: []byte{}}
+				nonce = m[2]
+			}
+			continue
+		}
+		if m := end.FindStringSubmatch(bare); m != nil && closes(open.Path, strings.TrimSpace(m[1])) && m[2] == nonce {
+			open.Path = strings.TrimSpace(m[1])
+			files = append(files, *open)
+			open = nil
+			continue
+		}
+		open.Content = append(open.Content, line...)
+	}
+	if open != nil {
+		return nil, fmt.Errorf("section for %s has no END marker", open.Path)
+	}
+	return files, nil
+}
+
+// closes reports whether an END marker for path closes a section whose
+// START marker names start, which may add the revision as "path@revision".
+func closes(start, path string) bool {
+	return start == path || strings.HasPrefix(start, path+"@")
+}
+
+// parseXML extracts <document> elements from the first <documents> element.
+func parseXML(data []byte) ([]File, error) {
+	i := bytes.Index(data, []byte("<documents>"))
+	if i < 0 {
+		return nil, fmt.Errorf("no <documents> element found")
+	}
+	var parsed struct {
+		Documents []struct {
+			Source  string `xml:"source"`
+			Content string `xml:"document_content"`
+		} `xml:"document"`
+	}
+	if err := xml.NewDecoder(bytes.NewReader(data[i:])).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("invalid XML: %v", err)
+	}
+	var files []File
+	for _, d := range parsed.Documents {
+		content := strings.TrimPrefix(d.Content, "\n")
+		files = append(files, File{Path: strings.TrimSpace(d.Source), Content: []byte(content)})
+	}
+	return files, nil
+}
+
+// markdownHeading matches a "## path" heading followed by the opening fence
+// of a code block.
+var markdownHeading = regexp.MustCompile("(?m)^## .+\n\n?(```+)[^`\n]*$")
+
+// markdownTreeHeading is the heading of the file tree, which is not a file.
+const markdownTreeHeading = "File tree"
+
+// parseMarkdown extracts "## path" headings each followed by a fenced block.
+func parseMarkdown(data []byte) ([]File, error) {
+	lines := diff.SplitLines(string(data))
+	var files []File
+	for i := 0; i < len(lines); i++ {
+		heading, ok := strings.CutPrefix(strings.TrimRight(lines[i], "\r\n"), "## ")
+		if !ok {
+			continue
+		}
+		j := i + 1
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		if j == len(lines) {
+			break
+		}
+		opening := strings.TrimRight(lines[j], "\r\n")
+		fence := opening[:len(opening)-len(strings.TrimLeft(opening, "`"))]
+		if len(fence) < 3 {
+			continue
+		}
+		content := []byte{}
+		closed := false
+		k := j + 1
+		for ; k < len(lines); k++ {
+			if strings.TrimRight(lines[k], " \t\r\n") == fence {
+				closed = true
+				break
+			}
+			content = append(content, lines[k]...)
+		}
+		if !closed {
+			return nil, fmt.Errorf("code block for %s is not closed", heading)
+		}
+		if heading != markdownTreeHeading {
+			files = append(files, File{Path: strings.TrimSpace(heading), Content: content})
+		}
+		i = k
+	}
+	return files, nil
+}
+
+// jsonFile mirrors the objects written by the json and jsonl formats.
+type jsonFile struct {
+	Path     string `json:"path"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+func (f jsonFile) file() (File, error) {
+	if f.Path == "" {
+		return File{}, fmt.Errorf("file object without a path")
+	}
+	if f.Encoding == "base64" {
+		data, err := base64.StdEncoding.DecodeString(f.Content)
+		if err != nil {
+			return File{}, fmt.Errorf("%s: %v", f.Path, err)
+		}
+		return File{Path: f.Path, Content: data}, nil
+	}
+	return File{Path: f.Path, Content: []byte(f.Content)}, nil
+}
+
+// parseJSON extracts the files array of a {"files": [...]} object.
+func parseJSON(data []byte) ([]File, error) {
+	var parsed struct {
+		Files []jsonFile `json:"files"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	var files []File
+	for _, f := range parsed.Files {
+		file, err := f.file()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// parseJSONL extracts one file object per non-blank line.
+func parseJSONL(data []byte) ([]File, error) {
+	var files []File
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var f jsonFile
+		if err := json.Unmarshal(line, &f); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %v", n, err)
+		}
+		file, err := f.file()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		files = append(files, file)
+	}
+	return files, sc.Err()
+}
@@ -0,0 +1,90 @@
+package unpack
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matthewchivers/txt2llm/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseRoundTrip verifies that every output format, including collision nonces, CDATA, base64 content, a tree
+// and a revision note, parses back to the original files with format detection.
+func TestParseRoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	contents := map[string]string{
+		"main.go":        "package main\n",
+		"docs/README.md": "# Title\n\n```sh\nmake\n```\n",
+		"tricky.txt":     "<<<END:tricky.txt>>>\n]]> & <tags>\n",
+		"empty.txt":      "",
+		"ends.txt":       "a]]>b\n",
+	}
+	var files, outPaths []string
+	var expected []File
+	for _, name := range []string{"main.go", "docs/README.md", "tricky.txt", "empty.txt", "ends.txt"} {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(contents[name]), 0644))
+		files = append(files, path)
+		outPaths = append(outPaths, name)
+		expected = append(expected, File{Path: name, Content: []byte(contents[name])})
+	}
+
+	for _, format := range []string{"markers", "xml", "markdown", "json", "jsonl"} {
+		t.Run(format, func(t *testing.T) {
+			f, err := output.ParseFormat(format)
+			require.NoError(t, err)
+			var buf bytes.Buffer
+			_, err = output.Render(context.Background(), &buf, files, outPaths,
+				output.Options{Format: f, MarkerPrefix: "<<<", MarkerSuffix: ">>>", Tree: f != output.FormatJSONL, Revision: "v1 (abc1234)"})
+			require.NoError(t, err)
+
+			got, err := Parse(buf.Bytes(), Options{Detect: true, MarkerPrefix: "<<<", MarkerSuffix: ">>>"})
+			require.NoError(t, err)
+			assert.Equal(t, expected, got)
+		})
+	}
+}
+
+// TestParseMarkers verifies that prose around sections is ignored, custom markers are honoured, a revision in a
+// START marker is dropped from the path and malformed input is rejected.
+func TestParseMarkers(t *testing.T) {
+	reply := "Sure! Here are the changes:\n\n" +
+		"[[START:a.go]]\npackage a\n[[END:a.go]]\n\n" +
+		"Let me know if you need more.\n" +
+		"[[START: b.go ]]  \npackage b\n[[END: b.go ]]\n" +
+		"[[START:c@2x.go@v1 (abc1234)]]\npackage c\n[[END:c@2x.go]]\n"
+	files, err := Parse([]byte(reply), Options{MarkerPrefix: "[[", MarkerSuffix: "]]"})
+	require.NoError(t, err)
+	assert.Equal(t, []File{
+		{Path: "a.go", Content: []byte("package a\n")},
+		{Path: "b.go", Content: []byte("package b\n")},
+		{Path: "c@2x.go", Content: []byte("package c\n")},
+	}, files)
+
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{name: "no sections", text: "nothing here\n", wantErr: "no file sections found"},
+		{name: "unclosed", text: "<<<START:a.go>>>\npackage a\n", wantErr: "a.go has no END marker"},
+		{name: "duplicate", text: "<<<START:a>>>\n<<<END:a>>>\n<<<START:a>>>\n<<<END:a>>>\n", wantErr: "more than one section for a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.text), Options{MarkerPrefix: "<<<", MarkerSuffix: ">>>"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+// TestParseMarkdownUnclosedFence verifies that a code block without a closing fence is reported.
+func TestParseMarkdownUnclosedFence(t *testing.T) {
+	_, err := Parse([]byte("## a.go\n\n```go\npackage a\n"), Options{Format: output.FormatMarkdown})
+	assert.ErrorContains(t, err, "code block for a.go is not closed")
+}
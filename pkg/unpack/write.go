@@ -0,0 +1,144 @@
+package unpack
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/matthewchivers/txt2llm/pkg/diff"
+	"github.com/matthewchivers/txt2llm/pkg/output"
+)
+
+// Change describes writing one file under a root directory.
+type Change struct {
+	File
+	// Target is the absolute path the file is written to.
+	Target string
+	// Exists reports whether Target already exists; Old is its content.
+	Exists bool
+	Old    []byte
+}
+
+// Status returns "new", "modified" or "unchanged".
+func (c Change) Status() string {
+	switch {
+	case !c.Exists:
+		return "new"
+	case bytes.Equal(c.Old, c.Content):
+		return "unchanged"
+	}
+	return "modified"
+}
+
+// Diff returns a unified diff from the current content to the new content,
+// or "" if they are equal.
+func (c Change) Diff() string {
+	oldName := "a/" + filepath.ToSlash(c.Path)
+	if !c.Exists {
+		oldName = "/dev/null"
+	}
+	return diff.Unified(oldName, "b/"+filepath.ToSlash(c.Path), c.Old, c.Content)
+}
+
+// Plan resolves where each file would be written under root and reads what
+// is there now. Relative paths are taken relative to root; absolute paths
+// must already lie inside it. It fails without writing anything if any path
+// escapes root, including through a symlink.
+func Plan(root string, files []File) ([]Change, error) {
+	rootAbs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	rootReal, err := filepath.EvalSymlinks(rootAbs)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, f := range files {
+		target, err := resolveTarget(rootAbs, rootReal, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		c := Change{File: f, Target: target}
+		switch info, err := os.Stat(target); {
+		case err == nil && info.IsDir():
+			return nil, fmt.Errorf("%s is a directory", f.Path)
+		case err == nil:
+			if c.Old, err = os.ReadFile(target); err != nil {
+				return nil, err
+			}
+			c.Exists = true
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// resolveTarget returns the absolute path for path under rootAbs, checking
+// that it stays inside the root both lexically and after following any
+// symlinks in the part of the path that already exists.
+func resolveTarget(rootAbs, rootReal, path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("empty file path")
+	}
+	target := filepath.FromSlash(path)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(rootAbs, target)
+	}
+	target = filepath.Clean(target)
+	if !within(rootAbs, target) {
+		return "", fmt.Errorf("refusing to write %s: it is outside %s", path, rootAbs)
+	}
+
+	existing := target
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	if !within(rootReal, real) {
+		return "", fmt.Errorf("refusing to write %s: a symlink leads outside %s", path, rootAbs)
+	}
+	return target, nil
+}
+
+// within reports whether path is root or inside it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Apply writes every new or modified file atomically, creating directories
+// as needed.
+func Apply(changes []Change) error {
+	for _, c := range changes {
+		if c.Status() == "unchanged" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(c.Target), 0755); err != nil {
+			return err
+		}
+		out, err := output.CreateAtomic(c.Target)
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(c.Content); err != nil {
+			out.Abort()
+			return err
+		}
+		if err := out.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
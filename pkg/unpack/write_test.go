@@ -0,0 +1,67 @@
+package unpack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPlanAndApply verifies statuses, diffs and that Apply writes new and modified files under the root.
+func TestPlanAndApply(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "same.txt"), []byte("same\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "old.txt"), []byte("old\n"), 0600))
+
+	changes, err := Plan(root, []File{
+		{Path: "same.txt", Content: []byte("same\n")},
+		{Path: "old.txt", Content: []byte("new\n")},
+		{Path: "dir/new.txt", Content: []byte("fresh\n")},
+		{Path: filepath.Join(root, "abs.txt"), Content: []byte("absolute\n")},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+	assert.Equal(t, "unchanged", changes[0].Status())
+	assert.Equal(t, "modified", changes[1].Status())
+	assert.Equal(t, "new", changes[2].Status())
+	assert.Equal(t, "--- a/old.txt\n+++ b/old.txt\n@@ -1 +1 @@\n-old\n+new\n", changes[1].Diff())
+	assert.Equal(t, "--- /dev/null\n+++ b/dir/new.txt\n@@ -0,0 +1 @@\n+fresh\n", changes[2].Diff())
+	assert.Empty(t, changes[0].Diff())
+
+	require.NoError(t, Apply(changes))
+	for path, want := range map[string]string{"old.txt": "new\n", "dir/new.txt": "fresh\n", "abs.txt": "absolute\n"} {
+		data, err := os.ReadFile(filepath.Join(root, path))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(data))
+	}
+	info, err := os.Stat(filepath.Join(root, "old.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "existing permissions are kept")
+}
+
+// TestPlanRefusesEscapes verifies that paths leaving the root, directly or through a symlink, are refused.
+func TestPlanRefusesEscapes(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "link")))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0755))
+
+	for _, path := range []string{
+		"../escape.txt",
+		"sub/../../escape.txt",
+		filepath.Join(outside, "abs.txt"),
+		"link/evil.txt",
+		"sub",
+		"",
+	} {
+		t.Run(path, func(t *testing.T) {
+			_, err := Plan(root, []File{{Path: "ok.txt"}, {Path: path, Content: []byte("x")}})
+			assert.Error(t, err)
+		})
+	}
+	entries, err := os.ReadDir(outside)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
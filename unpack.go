@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/matthewchivers/txt2llm/pkg/cli"
+	"github.com/matthewchivers/txt2llm/pkg/output"
+	"github.com/matthewchivers/txt2llm/pkg/unpack"
+)
+
+// runUnpack writes the files in a bundle read from a file or stdin under the
+// configured root, or previews what would change.
+func runUnpack(args []string, stdin io.Reader, stdout io.Writer) error {
+	cfg, input, err := cli.ParseUnpackArgs(args)
+	if err != nil {
+		return err
+	}
+	opts := unpack.Options{Detect: cfg.Format == "auto", MarkerPrefix: cfg.MarkerPrefix, MarkerSuffix: cfg.MarkerSuffix}
+	if !opts.Detect {
+		if opts.Format, err = output.ParseFormat(cfg.Format); err != nil {
+			return err
+		}
+	}
+
+	var data []byte
+	if input == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(input)
+	}
+	if err != nil {
+		return err
+	}
+	files, err := unpack.Parse(data, opts)
+	if err != nil {
+		return err
+	}
+	changes, err := unpack.Plan(cfg.Root, files)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cfg.Diff:
+		for _, c := range changes {
+			fmt.Fprint(stdout, c.Diff())
+		}
+		return nil
+	case cfg.DryRun:
+		for _, c := range changes {
+			fmt.Fprintf(stdout, "%-9s  %s\n", c.Status(), c.Path)
+		}
+		return nil
+	}
+	if err := unpack.Apply(changes); err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.Status()]++
+	}
+	fmt.Fprintf(os.Stderr, "Unpacked %d files under %s (%d new, %d modified, %d unchanged)\n",
+		len(changes), cfg.Root, counts["new"], counts["modified"], counts["unchanged"])
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunUnpack verifies that unpack previews changes with --dry-run and --diff and writes files otherwise.
+func TestRunUnpack(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("old\n"), 0644))
+	reply := "Here you go:\n<<<START:a.txt>>>\nnew\n<<<END:a.txt>>>\n<<<START:b/c.txt>>>\nc\n<<<END:b/c.txt>>>\n"
+
+	var out bytes.Buffer
+	require.NoError(t, runUnpack([]string{"--root", root, "--dry-run"}, strings.NewReader(reply), &out))
+	assert.Equal(t, "modified   a.txt\nnew        b/c.txt\n", out.String())
+
+	out.Reset()
+	require.NoError(t, runUnpack([]string{"--root", root, "--diff"}, strings.NewReader(reply), &out))
+	assert.Contains(t, out.String(), "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-old\n+new\n")
+	assert.Contains(t, out.String(), "--- /dev/null\n+++ b/b/c.txt\n")
+
+	data, err := os.ReadFile(filepath.Join(root, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "old\n", string(data), "previews must not write")
+
+	input := filepath.Join(t.TempDir(), "reply.txt")
+	require.NoError(t, os.WriteFile(input, []byte(reply), 0644))
+	require.NoError(t, runUnpack([]string{"--root", root, input}, strings.NewReader(""), &out))
+	data, err = os.ReadFile(filepath.Join(root, "b", "c.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "c\n", string(data))
+
+	err = runUnpack([]string{"--root", root}, strings.NewReader("<<<START:../x>>>\nx\n<<<END:../x>>>\n"), &out)
+	assert.ErrorContains(t, err, "outside")
+}